@@ -0,0 +1,384 @@
+// Package absence implements the rolling-window absence calculation: how
+// many days were spent abroad in any window of a given length, and how that
+// compares to the allowed limit.
+package absence
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Trip represents a single trip abroad
+type Trip struct {
+	Start       time.Time
+	End         time.Time
+	Days        int
+	Destination string
+
+	// ByWeek marks trips given as ISO weeks, whose dates are only known
+	// to the week
+	ByWeek bool
+
+	// Projected marks hypothetical trips added for what-if planning
+	Projected bool
+
+	// Ongoing marks a trip with no end date yet, which ends on the target date
+	Ongoing bool
+
+	// Excluded, when set, is why the trip is listed but counts no days
+	Excluded string
+
+	// Clipped marks a trip cut short to a date range, so that only its
+	// days inside the range count
+	Clipped bool
+
+	// Person is who took the trip, when trips of several people are
+	// recorded together
+	Person string
+}
+
+// DayCount selects which days of a trip count as days abroad
+type DayCount string
+
+const (
+	// Inclusive counts every day from departure to return (the default)
+	Inclusive DayCount = "inclusive"
+	// Exclusive counts neither the departure nor the return day
+	Exclusive DayCount = "exclusive"
+	// DepartureOnly counts the departure day but not the return day
+	DepartureOnly DayCount = "departure-only"
+)
+
+// CountedRange returns the first and last day of trip that count under
+// mode; last is before first when no day counts, as for an excluded trip
+func CountedRange(trip Trip, mode DayCount) (first, last time.Time) {
+	if trip.Excluded != "" {
+		return trip.Start, trip.Start.AddDate(0, 0, -1)
+	}
+	switch mode {
+	case Exclusive:
+		return trip.Start.AddDate(0, 0, 1), trip.End.AddDate(0, 0, -1)
+	case DepartureOnly:
+		return trip.Start, trip.End.AddDate(0, 0, -1)
+	}
+	return trip.Start, trip.End
+}
+
+// TripDays returns the number of days of trip that count under mode
+func TripDays(trip Trip, mode DayCount) int {
+	first, last := CountedRange(trip, mode)
+	return max(DaysBetween(first, last)+1, 0)
+}
+
+// Anchor selects which day of a trip its per-trip window ends on
+type Anchor string
+
+const (
+	// AnchorEnd ends each trip's window on its end date (the default)
+	AnchorEnd Anchor = "end"
+	// AnchorStart ends each trip's window on its start date
+	AnchorStart Anchor = "start"
+)
+
+// Config holds the rule the trips are checked against
+type Config struct {
+	WindowMonths int
+	AbsenceLimit int
+
+	// WindowDays, when positive, replaces WindowMonths with a window of
+	// that many days, such as Schengen's 180
+	WindowDays int
+
+	// DayCount selects which days of each trip count; empty means Inclusive
+	DayCount DayCount
+
+	// Anchor selects the day each per-trip window ends on; empty means
+	// AnchorEnd
+	Anchor Anchor
+
+	// WarnAt, when positive, is the days remaining at or below which the
+	// status is "caution" instead of the default threshold
+	WarnAt int
+
+	// ExcludeAnchorTrip leaves the anchoring trip's own days out of its
+	// per-trip window total, so only prior absences are counted
+	ExcludeAnchorTrip bool
+}
+
+// TripResult is the per-trip analysis of one trip: the days abroad in the
+// rolling window ending on the trip's end date
+type TripResult struct {
+	Trip          Trip
+	WindowStart   time.Time
+	WindowEnd     time.Time
+	DaysInWindow  int
+	DaysRemaining int
+}
+
+// Result is the full analysis of a trip history on a target date
+type Result struct {
+	Trips []TripResult
+
+	// Standing in the window ending on the target date
+	TargetDate       time.Time
+	WindowStart      time.Time
+	TotalDaysOutside int
+	DaysRemaining    int
+	Status           string
+
+	// Window with the most days outside, ending on or before the target date
+	PeakDaysOutside int
+	PeakWindowStart time.Time
+	PeakWindowEnd   time.Time
+}
+
+// Analyze computes the per-trip analysis, the standing on targetDate and
+// the peak window for trips
+func Analyze(trips []Trip, targetDate time.Time, config Config) Result {
+	result := Result{TargetDate: targetDate}
+
+	for _, trip := range trips {
+		daysInWindow := TripWindowDays(trips, trip, config)
+		windowStart, windowEnd := TripWindow(trip, config)
+		result.Trips = append(result.Trips, TripResult{
+			Trip:          trip,
+			WindowStart:   windowStart,
+			WindowEnd:     windowEnd,
+			DaysInWindow:  daysInWindow,
+			DaysRemaining: config.AbsenceLimit - daysInWindow,
+		})
+	}
+
+	result.WindowStart = WindowStart(targetDate, config)
+	result.TotalDaysOutside = CalculateDaysInWindow(trips, result.WindowStart, targetDate, config.DayCount)
+	result.DaysRemaining = config.AbsenceLimit - result.TotalDaysOutside
+	result.Status = Status(result.DaysRemaining, config)
+
+	if len(trips) > 0 {
+		result.PeakDaysOutside, result.PeakWindowEnd = PeakWindow(trips, targetDate, config)
+		result.PeakWindowStart = WindowStart(result.PeakWindowEnd, config)
+	}
+
+	return result
+}
+
+// AddMonths adds months to a date
+func AddMonths(t time.Time, months int) time.Time {
+	year, month, day := t.Date()
+	month += time.Month(months)
+
+	// Normalize year and month
+	for month > 12 {
+		month -= 12
+		year++
+	}
+	for month < 1 {
+		month += 12
+		year--
+	}
+
+	// Clamp the day to the target month's length (e.g. 31 March - 1 month
+	// = 28 or 29 February). Day 0 of the next month is the last day of this
+	// one; for December, month 13 normalizes to January of the next year.
+	maxDay := time.Date(year, month+1, 0, 0, 0, 0, 0, t.Location()).Day()
+	if day > maxDay {
+		day = maxDay
+	}
+
+	return time.Date(year, month, day, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+}
+
+// TruncateToDay returns midnight UTC on t's calendar date in t's own zone,
+// matching how parsed trip dates are represented
+func TruncateToDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// DaysBetween returns the number of calendar days from a to b. Each is
+// reduced to its date first, so a 23- or 25-hour day across a daylight
+// saving change still counts as one day.
+func DaysBetween(a, b time.Time) int {
+	return int(TruncateToDay(b).Sub(TruncateToDay(a)).Hours() / 24)
+}
+
+// WindowStart returns the first day of the rolling window ending on end:
+// WindowDays days back including end itself, or WindowMonths months back
+func WindowStart(end time.Time, config Config) time.Time {
+	if config.WindowDays > 0 {
+		return end.AddDate(0, 0, -(config.WindowDays - 1))
+	}
+	return AddMonths(end, -config.WindowMonths)
+}
+
+// ForwardWindowEnd returns the last day of the window looking forward from
+// start: WindowDays days ahead including start, or WindowMonths months ahead
+func ForwardWindowEnd(start time.Time, config Config) time.Time {
+	if config.WindowDays > 0 {
+		return start.AddDate(0, 0, config.WindowDays-1)
+	}
+	return AddMonths(start, config.WindowMonths)
+}
+
+// WindowEnd returns the date one window length after start, when a window
+// starting then would have fully passed
+func WindowEnd(start time.Time, config Config) time.Time {
+	if config.WindowDays > 0 {
+		return start.AddDate(0, 0, config.WindowDays)
+	}
+	return AddMonths(start, config.WindowMonths)
+}
+
+// CalculateDaysInWindow calculates total days in a rolling window ending on
+// endDate, counting the days of each trip that mode counts. Days covered by
+// more than one trip are counted once.
+func CalculateDaysInWindow(trips []Trip, windowStart, windowEnd time.Time, mode DayCount) int {
+	total, _ := WindowContributions(trips, windowStart, windowEnd, mode)
+	return total
+}
+
+// Contribution is the number of days one trip adds to a window's total
+type Contribution struct {
+	Trip Trip
+	Days int
+}
+
+// WindowContributions is CalculateDaysInWindow that also returns each
+// overlapping trip's share of the total, in order of start date. A day
+// covered by more than one trip is credited to the trip that starts first.
+func WindowContributions(trips []Trip, windowStart, windowEnd time.Time, mode DayCount) (total int, contributions []Contribution) {
+	// Clip each trip to the window
+	type clipped struct {
+		trip        Trip
+		first, last time.Time
+	}
+	var ranges []clipped
+	for _, trip := range trips {
+		first, last := CountedRange(trip, mode)
+
+		// Check if trip overlaps with window
+		if last.Before(windowStart) || first.After(windowEnd) {
+			continue
+		}
+
+		overlapStart := maxTime(first, windowStart)
+		overlapEnd := minTime(last, windowEnd)
+		if !overlapEnd.Before(overlapStart) {
+			ranges = append(ranges, clipped{trip, overlapStart, overlapEnd})
+		}
+	}
+
+	// Walk the ranges by start date, counting only the days past the
+	// furthest day already counted
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].first.Before(ranges[j].first)
+	})
+
+	var counted time.Time
+	for _, r := range ranges {
+		first := r.first
+		if !counted.IsZero() && !first.After(counted) {
+			first = counted.AddDate(0, 0, 1)
+		}
+
+		days := 0
+		if !r.last.Before(first) {
+			days = DaysBetween(first, r.last) + 1
+			counted = r.last
+		}
+		total += days
+		contributions = append(contributions, Contribution{Trip: r.trip, Days: days})
+	}
+
+	return total, contributions
+}
+
+// TripWindowDays calculates the per-trip analysis total for the rolling
+// window ending on trip's end date, or its start date with AnchorStart
+func TripWindowDays(trips []Trip, trip Trip, config Config) int {
+	start, end := TripWindow(trip, config)
+
+	if config.ExcludeAnchorTrip {
+		// Leave out the anchor itself rather than subtracting its days, which
+		// would also remove days another trip overlapping it covers
+		others := make([]Trip, 0, len(trips))
+		excluded := false
+		for _, other := range trips {
+			if !excluded && other == trip {
+				excluded = true
+				continue
+			}
+			others = append(others, other)
+		}
+		return CalculateDaysInWindow(others, start, end, config.DayCount)
+	}
+
+	return CalculateDaysInWindow(trips, start, end, config.DayCount)
+}
+
+// TripWindow returns the first and last day of the rolling window anchored
+// on trip: ending on its end date, or its start date with AnchorStart
+func TripWindow(trip Trip, config Config) (start, end time.Time) {
+	end = trip.End
+	if config.Anchor == AnchorStart {
+		end = trip.Start
+	}
+	return WindowStart(end, config), end
+}
+
+// CautionThreshold returns the days remaining at or below which the status
+// is "caution": WarnAt when set, otherwise just under 15% of the limit or
+// 30 days, whichever is smaller
+func CautionThreshold(config Config) int {
+	if config.WarnAt > 0 {
+		return config.WarnAt
+	}
+	return int(math.Min(30, math.Ceil(float64(config.AbsenceLimit)*0.15))) - 1
+}
+
+// Status classifies the remaining days as "ok", "caution" or "exceeded"
+func Status(remainingDays int, config Config) string {
+	if remainingDays < 0 {
+		return "exceeded"
+	} else if remainingDays <= CautionThreshold(config) {
+		return "caution"
+	}
+	return "ok"
+}
+
+// PeakWindow slides the rolling window a day at a time over the history and
+// returns the highest total among the windows ending between the first
+// trip's start and to, and the day that window ends
+func PeakWindow(trips []Trip, to time.Time, config Config) (total int, end time.Time) {
+	first := trips[0].Start
+	for _, trip := range trips {
+		first = minTime(first, trip.Start)
+	}
+
+	to = TruncateToDay(to)
+	end = to
+	for day := first; !day.After(to); day = day.AddDate(0, 0, 1) {
+		if days := CalculateDaysInWindow(trips, WindowStart(day, config), day, config.DayCount); days > total {
+			total, end = days, day
+		}
+	}
+
+	return total, end
+}
+
+// maxTime returns the later of two times
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// minTime returns the earlier of two times
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,462 @@
+package absence
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata" // for Europe/London in TestDaysAcrossDST
+)
+
+func TestCalculateDaysInDayWindow(t *testing.T) {
+	trips := []Trip{
+		{Start: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC), Days: 10},
+		{Start: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC), Days: 30},
+	}
+	config := Config{WindowMonths: 12, WindowDays: 180}
+
+	// The 180 days ending 29.06.2025 start on 01.01.2025, so the whole first
+	// trip and 29 days of the second count; a day later 01.01 drops out as
+	// 30.06 comes in
+	end := time.Date(2025, 6, 29, 0, 0, 0, 0, time.UTC)
+	if got := CalculateDaysInWindow(trips, WindowStart(end, config), end, Inclusive); got != 39 {
+		t.Errorf("180-day window ending 29.06.2025 has %d days, want 39", got)
+	}
+	end = end.AddDate(0, 0, 1)
+	if got := CalculateDaysInWindow(trips, WindowStart(end, config), end, Inclusive); got != 39 {
+		t.Errorf("180-day window ending 30.06.2025 has %d days, want 39", got)
+	}
+}
+
+func TestForwardWindowEnd(t *testing.T) {
+	start := time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+	if got := ForwardWindowEnd(start, Config{WindowMonths: 1}); !got.Equal(time.Date(2025, 2, 28, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("1 month from 31.01.2025 ends %s, want 28.02.2025", got.Format("02.01.2006"))
+	}
+
+	// Mirrors WindowStart: the window ending on the forward window's last
+	// day starts on start
+	config := Config{WindowMonths: 12, WindowDays: 180}
+	if end := ForwardWindowEnd(start, config); !WindowStart(end, config).Equal(start) {
+		t.Errorf("180 days from 31.01.2025 end %s, whose window starts %s",
+			end.Format("02.01.2006"), WindowStart(end, config).Format("02.01.2006"))
+	}
+}
+
+func TestParseISOWeekRejectsMissingWeek53(t *testing.T) {
+	if _, _, err := ParseISOWeek("2021-W53"); err == nil {
+		t.Error("2021-W53 parsed, but 2021 has only 52 ISO weeks")
+	}
+	if _, _, err := ParseISOWeek("2020-W53"); err != nil {
+		t.Errorf("2020-W53: %v", err)
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	trips := []Trip{
+		{Start: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2024, 7, 30, 0, 0, 0, 0, time.UTC), Days: 212},
+		{Start: time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2025, 12, 5, 0, 0, 0, 0, time.UTC), Days: 5},
+	}
+	target := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	result := Analyze(trips, target, Config{WindowMonths: 12, AbsenceLimit: 180})
+
+	if result.TotalDaysOutside != 5 || result.DaysRemaining != 175 || result.Status != "ok" {
+		t.Errorf("status = %d outside, %d remaining, %s; want 5, 175, ok",
+			result.TotalDaysOutside, result.DaysRemaining, result.Status)
+	}
+	if result.Trips[0].DaysRemaining != -32 {
+		t.Errorf("first trip has %d days remaining, want -32", result.Trips[0].DaysRemaining)
+	}
+	if result.PeakDaysOutside != 212 || !result.PeakWindowEnd.Equal(trips[0].End) {
+		t.Errorf("peak = %d days ending %s, want 212 ending 30.07.2024",
+			result.PeakDaysOutside, result.PeakWindowEnd.Format("02.01.2006"))
+	}
+}
+
+func TestStatusThresholds(t *testing.T) {
+	for _, tc := range []struct {
+		warnAt, remaining int
+		want              string
+	}{
+		// Default for a 180-day limit: caution under 27 days (15%)
+		{0, 27, "ok"},
+		{0, 26, "caution"},
+		{0, 0, "caution"},
+		{0, -1, "exceeded"},
+		{50, 51, "ok"},
+		{50, 50, "caution"},
+		{50, -1, "exceeded"},
+	} {
+		config := Config{AbsenceLimit: 180, WarnAt: tc.warnAt}
+		if got := Status(tc.remaining, config); got != tc.want {
+			t.Errorf("warn-at %d, %d remaining: status %q, want %q", tc.warnAt, tc.remaining, got, tc.want)
+		}
+	}
+}
+
+func TestTripWindowDaysAnchor(t *testing.T) {
+	trips := []Trip{
+		{Start: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)},
+		{Start: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2025, 3, 20, 0, 0, 0, 0, time.UTC)},
+	}
+
+	// Anchored on its start, the second trip's window holds only its first day
+	for _, tc := range []struct {
+		anchor Anchor
+		want   int
+	}{
+		{"", 30},
+		{AnchorEnd, 30},
+		{AnchorStart, 11},
+	} {
+		config := Config{WindowMonths: 12, AbsenceLimit: 180, Anchor: tc.anchor}
+		if got := TripWindowDays(trips, trips[1], config); got != tc.want {
+			t.Errorf("anchor %q: %d days in window, want %d", tc.anchor, got, tc.want)
+		}
+	}
+
+	start, end := TripWindow(trips[1], Config{WindowMonths: 12, Anchor: AnchorStart})
+	if !start.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) || !end.Equal(trips[1].Start) {
+		t.Errorf("start-anchored window = %s to %s, want 01.03.2024 to 01.03.2025",
+			start.Format("02.01.2006"), end.Format("02.01.2006"))
+	}
+}
+
+func TestAddMonths(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+
+	for _, tc := range []struct {
+		from   time.Time
+		months int
+		want   time.Time
+	}{
+		// 31-day months into shorter ones clamp to the last day
+		{date(2023, 3, 31), -1, date(2023, 2, 28)},
+		{date(2024, 3, 31), -1, date(2024, 2, 29)},
+		{date(2023, 1, 31), 1, date(2023, 2, 28)},
+		{date(2024, 1, 31), 1, date(2024, 2, 29)},
+		{date(2023, 5, 31), -1, date(2023, 4, 30)},
+		{date(2023, 10, 31), 1, date(2023, 11, 30)},
+		{date(2023, 3, 30), -1, date(2023, 2, 28)},
+		{date(2024, 3, 29), -1, date(2024, 2, 29)},
+
+		// Across the year boundary, in and out of December
+		{date(2024, 1, 31), -1, date(2023, 12, 31)},
+		{date(2023, 12, 31), 1, date(2024, 1, 31)},
+		{date(2023, 12, 31), 2, date(2024, 2, 29)},
+		{date(2023, 11, 30), 1, date(2023, 12, 30)},
+		{date(2024, 2, 29), -2, date(2023, 12, 29)},
+
+		// Whole years keep the day, except 29 February in common years
+		{date(2023, 12, 31), -12, date(2022, 12, 31)},
+		{date(2024, 5, 31), -60, date(2019, 5, 31)},
+		{date(2022, 8, 31), 18, date(2024, 2, 29)},
+	} {
+		if got := AddMonths(tc.from, tc.months); !got.Equal(tc.want) {
+			t.Errorf("AddMonths(%s, %d) = %s, want %s",
+				tc.from.Format("02.01.2006"), tc.months, got.Format("02.01.2006"), tc.want.Format("02.01.2006"))
+		}
+	}
+}
+
+func TestLeapDay(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+
+	// 29 February moves to 28 February in common years and stays in leap years
+	for _, tc := range []struct {
+		from   time.Time
+		months int
+		want   time.Time
+	}{
+		{date(2024, 2, 29), -12, date(2023, 2, 28)},
+		{date(2024, 2, 29), 12, date(2025, 2, 28)},
+		{date(2024, 2, 29), -48, date(2020, 2, 29)},
+		{date(2024, 2, 29), 48, date(2028, 2, 29)},
+		{date(2023, 2, 28), 12, date(2024, 2, 28)},
+		{date(2025, 2, 28), -12, date(2024, 2, 28)},
+	} {
+		if got := AddMonths(tc.from, tc.months); !got.Equal(tc.want) {
+			t.Errorf("AddMonths(%s, %d) = %s, want %s",
+				tc.from.Format("02.01.2006"), tc.months, got.Format("02.01.2006"), tc.want.Format("02.01.2006"))
+		}
+	}
+
+	// A trip across the end of February counts the leap day
+	leap := Trip{Start: date(2024, 2, 27), End: date(2024, 3, 2)}
+	common := Trip{Start: date(2023, 2, 27), End: date(2023, 3, 2)}
+	if got := TripDays(leap, Inclusive); got != 5 {
+		t.Errorf("27.02.2024–02.03.2024 counts %d days, want 5", got)
+	}
+	if got := TripDays(common, Inclusive); got != 4 {
+		t.Errorf("27.02.2023–02.03.2023 counts %d days, want 4", got)
+	}
+	if got := TripDays(Trip{Start: date(2024, 1, 1), End: date(2024, 12, 31)}, Inclusive); got != 366 {
+		t.Errorf("all of 2024 counts %d days, want 366", got)
+	}
+
+	// Windows starting or ending on the leap day include it exactly once
+	trips := []Trip{leap}
+	if got := CalculateDaysInWindow(trips, date(2024, 2, 29), date(2024, 2, 29), Inclusive); got != 1 {
+		t.Errorf("window of just 29.02.2024 has %d days, want 1", got)
+	}
+	if got := CalculateDaysInWindow(trips, date(2023, 3, 1), date(2024, 2, 29), Inclusive); got != 3 {
+		t.Errorf("window ending 29.02.2024 has %d days, want 3", got)
+	}
+	if got := CalculateDaysInWindow(trips, date(2024, 2, 29), date(2025, 2, 28), Inclusive); got != 3 {
+		t.Errorf("window starting 29.02.2024 has %d days, want 3", got)
+	}
+
+	// A 180-day window over the leap day is still 180 days long
+	config := Config{WindowDays: 180, AbsenceLimit: 90}
+	end := date(2024, 3, 31)
+	if start := WindowStart(end, config); DaysBetween(start, end)+1 != 180 {
+		t.Errorf("180-day window ending 31.03.2024 starts %s", start.Format("02.01.2006"))
+	}
+	if got := CalculateDaysInWindow([]Trip{{Start: date(2023, 1, 1), End: date(2024, 12, 31)}}, WindowStart(end, config), end, Inclusive); got != 180 {
+		t.Errorf("180-day window fully abroad has %d days, want 180", got)
+	}
+}
+
+func TestDayCountModes(t *testing.T) {
+	trips := []Trip{
+		{Start: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)},
+		{Start: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	windowStart := time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)
+	windowEnd := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		mode             DayCount
+		tripDays, window int
+	}{
+		{Inclusive, 10, 11},
+		{Exclusive, 8, 8},
+		{DepartureOnly, 9, 9},
+	} {
+		if got := TripDays(trips[0], tc.mode); got != tc.tripDays {
+			t.Errorf("%s: 10-day trip counts %d days, want %d", tc.mode, got, tc.tripDays)
+		}
+		if got := CalculateDaysInWindow(trips, windowStart, windowEnd, tc.mode); got != tc.window {
+			t.Errorf("%s: window has %d days, want %d", tc.mode, got, tc.window)
+		}
+	}
+}
+
+func TestExcludedTripCountsNoDays(t *testing.T) {
+	trips := []Trip{
+		{Start: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)},
+		{Start: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), Excluded: "day trip"},
+	}
+	windowStart := time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)
+	windowEnd := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	if got := CalculateDaysInWindow(trips, windowStart, windowEnd, Inclusive); got != 10 {
+		t.Errorf("window has %d days, want 10 without the excluded trip", got)
+	}
+	if got := TripDays(trips[1], Inclusive); got != 0 {
+		t.Errorf("excluded trip counts %d days, want 0", got)
+	}
+}
+
+func TestWindowContributions(t *testing.T) {
+	trips := []Trip{
+		{Start: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)},
+		{Start: time.Date(2025, 1, 8, 0, 0, 0, 0, time.UTC), End: time.Date(2025, 1, 12, 0, 0, 0, 0, time.UTC)},
+		{Start: time.Date(2025, 1, 9, 0, 0, 0, 0, time.UTC), End: time.Date(2025, 1, 9, 0, 0, 0, 0, time.UTC)},
+		{Start: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)},
+	}
+
+	// The window cuts the first trip to 5–10 January and the last to 1–15 March
+	start, end := time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC), time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)
+	total, contributions := WindowContributions(trips, start, end, Inclusive)
+
+	want := []int{6, 2, 0, 15}
+	if total != 23 || len(contributions) != len(want) {
+		t.Fatalf("got %d days from %d trips, want 23 from %d", total, len(contributions), len(want))
+	}
+	for i, days := range want {
+		if contributions[i].Days != days {
+			t.Errorf("trip %d contributes %d days, want %d", i, contributions[i].Days, days)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	for _, tc := range []struct {
+		input string
+		want  string // dd.mm.yyyy, or empty for an error
+	}{
+		{"25.05.2023", "25.05.2023"},
+		{" 25.05.2023 ", "25.05.2023"},
+		{"25/05/2023", "25.05.2023"},
+		{"25-05-2023", "25.05.2023"},
+		{"2023-05-25", "25.05.2023"},
+		{"2023/05/25", "25.05.2023"},
+		{"2023.05.25", "25.05.2023"},
+		{"25 May 2023", "25.05.2023"},
+		{"25 September 2023", "25.09.2023"},
+
+		// Day first when both readings are valid; month first otherwise
+		{"01/02/2024", "01.02.2024"},
+		{"05/25/2023", "25.05.2023"},
+
+		// Timestamps keep the date as written, whatever the time or zone
+		{"2024-03-01T23:30:00-05:00", "01.03.2024"},
+		{"2024-03-01 00:15", "01.03.2024"},
+
+		{"29.02.2024", "29.02.2024"},
+		{"29.02.2023", ""},
+		{"31.04.2024", ""},
+		{"", ""},
+		{"tomorrow", ""},
+	} {
+		got, err := ParseDate(tc.input)
+		switch {
+		case tc.want == "" && err == nil:
+			t.Errorf("ParseDate(%q) = %s, want an error", tc.input, got.Format("02.01.2006"))
+		case tc.want != "" && err != nil:
+			t.Errorf("ParseDate(%q): %v", tc.input, err)
+		case tc.want != "" && got.Format("02.01.2006") != tc.want:
+			t.Errorf("ParseDate(%q) = %s, want %s", tc.input, got.Format("02.01.2006"), tc.want)
+		}
+	}
+}
+
+func TestCalculateDaysInWindow(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	windowStart, windowEnd := date(2024, 1, 1), date(2024, 12, 31)
+
+	for _, tc := range []struct {
+		name  string
+		trips []Trip
+		want  int
+	}{
+		{"no trips", nil, 0},
+		{"same-day trip counts one day", []Trip{{Start: date(2024, 5, 1), End: date(2024, 5, 1)}}, 1},
+		{"departure and return days both count", []Trip{{Start: date(2024, 5, 1), End: date(2024, 5, 10)}}, 10},
+		{"trip before the window", []Trip{{Start: date(2023, 6, 1), End: date(2023, 6, 30)}}, 0},
+		{"trip after the window", []Trip{{Start: date(2025, 1, 1), End: date(2025, 1, 5)}}, 0},
+		{"clipped at the window start", []Trip{{Start: date(2023, 12, 25), End: date(2024, 1, 3)}}, 3},
+		{"clipped at the window end", []Trip{{Start: date(2024, 12, 30), End: date(2025, 1, 3)}}, 2},
+		{"overlapping trips count shared days once", []Trip{
+			{Start: date(2024, 3, 1), End: date(2024, 3, 10)},
+			{Start: date(2024, 3, 5), End: date(2024, 3, 15)},
+		}, 15},
+		{"trips sharing a travel day", []Trip{
+			{Start: date(2024, 3, 1), End: date(2024, 3, 10)},
+			{Start: date(2024, 3, 10), End: date(2024, 3, 12)},
+		}, 12},
+		{"trip covering the whole leap-year window", []Trip{{Start: date(2023, 1, 1), End: date(2025, 12, 31)}}, 366},
+	} {
+		if got := CalculateDaysInWindow(tc.trips, windowStart, windowEnd, Inclusive); got != tc.want {
+			t.Errorf("%s: %d days, want %d", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestRoundToDay(t *testing.T) {
+	for _, tc := range []struct {
+		input              string
+		floor, ceil, round string // dd.mm.yyyy
+	}{
+		{"2024-03-01T00:00:00Z", "01.03.2024", "01.03.2024", "01.03.2024"},
+		{"2024-03-01T00:00:01Z", "01.03.2024", "02.03.2024", "01.03.2024"},
+		{"2024-03-01T11:59:59Z", "01.03.2024", "02.03.2024", "01.03.2024"},
+		{"2024-03-01T12:00:00Z", "01.03.2024", "02.03.2024", "02.03.2024"},
+		{"2024-12-31T23:30:00-05:00", "31.12.2024", "01.01.2025", "01.01.2025"},
+		{"2024-02-28 18:00", "28.02.2024", "29.02.2024", "29.02.2024"},
+	} {
+		ts, err := ParseTimestamp(tc.input)
+		if err != nil {
+			t.Fatalf("ParseTimestamp(%q): %v", tc.input, err)
+		}
+		for rounding, want := range map[Rounding]string{Floor: tc.floor, Ceil: tc.ceil, Round: tc.round} {
+			if got := RoundToDay(ts, rounding).Format("02.01.2006"); got != want {
+				t.Errorf("RoundToDay(%q, %s) = %s, want %s", tc.input, rounding, got, want)
+			}
+		}
+	}
+}
+
+func TestParseDateTwoDigitYears(t *testing.T) {
+	for input, want := range map[string]string{
+		"01.02.24":   "01.02.2024",
+		"01/02/24":   "01.02.2024",
+		"31-12-68":   "31.12.2068",
+		"01.01.69":   "01.01.1969",
+		"12/25/24":   "25.12.2024", // only valid month-first
+		"01.02.2024": "01.02.2024", // four digits still win
+	} {
+		got, err := ParseDate(input)
+		if err != nil {
+			t.Errorf("ParseDate(%q): %v", input, err)
+			continue
+		}
+		if got.Format("02.01.2006") != want {
+			t.Errorf("ParseDate(%q) = %s, want %s", input, got.Format("02.01.2006"), want)
+		}
+	}
+
+	if _, err := ParseDate("01.02.202"); err == nil {
+		t.Error("ParseDate(\"01.02.202\") succeeded, want an error for a three-digit year")
+	}
+}
+
+func TestDaysAcrossDST(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Clocks went forward on 30 March 2025, so 28 March to 2 April in London
+	// is 5 days less an hour apart
+	trip := Trip{
+		Start: time.Date(2025, 3, 28, 0, 0, 0, 0, london),
+		End:   time.Date(2025, 4, 2, 0, 0, 0, 0, london),
+	}
+	if got := TripDays(trip, Inclusive); got != 6 {
+		t.Errorf("TripDays = %d, want 6", got)
+	}
+
+	start, end := time.Date(2025, 3, 1, 0, 0, 0, 0, london), time.Date(2025, 4, 30, 0, 0, 0, 0, london)
+	if got := CalculateDaysInWindow([]Trip{trip}, start, end, Inclusive); got != 6 {
+		t.Errorf("CalculateDaysInWindow = %d, want 6", got)
+	}
+
+	// And back an hour on 26 October 2025
+	if got := DaysBetween(time.Date(2025, 10, 25, 0, 0, 0, 0, london), time.Date(2025, 10, 27, 0, 0, 0, 0, london)); got != 2 {
+		t.Errorf("DaysBetween across the autumn change = %d, want 2", got)
+	}
+}
+
+func TestTranslateMonths(t *testing.T) {
+	for _, tc := range []struct {
+		value, locale, want string
+	}{
+		{"02 Januar 2006", "de", "02.01.2006"},
+		{"15 März 2024", "de", "15.03.2024"},
+		{"01 Dez. 2023", "de", "01.12.2023"},
+		{"14 juillet 2024", "fr", "14.07.2024"},
+		{"03 février 2025", "fr", "03.02.2025"},
+		{"25 de diciembre de 2024", "es", "25.12.2024"},
+	} {
+		got, err := ParseDate(TranslateMonths(tc.value, tc.locale))
+		if err != nil {
+			t.Errorf("%s (%s): %v", tc.value, tc.locale, err)
+			continue
+		}
+		if got.Format("02.01.2006") != tc.want {
+			t.Errorf("%s (%s) = %s, want %s", tc.value, tc.locale, got.Format("02.01.2006"), tc.want)
+		}
+	}
+
+	// English stays the default: other locales' names are not translated
+	if _, err := ParseDate(TranslateMonths("02 Januar 2006", "en")); err == nil {
+		t.Error("02 Januar 2006 parsed without --locale de")
+	}
+}
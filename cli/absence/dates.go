@@ -0,0 +1,95 @@
+package absence
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DateFormats lists the supported date formats for parsing, tried in order
+var DateFormats = []string{
+	"02.01.2006",      // dd.mm.yyyy
+	"02/01/2006",      // dd/mm/yyyy
+	"02-01-2006",      // dd-mm-yyyy
+	"2006-01-02",      // yyyy-mm-dd
+	"2006/01/02",      // yyyy/01/02
+	"2006.01.02",      // yyyy.mm.dd
+	"01/02/2006",      // mm/dd/yyyy (US format)
+	"01-02-2006",      // mm-dd-yyyy
+	"02 Jan 2006",     // dd Mon yyyy
+	"02 January 2006", // dd Month yyyy
+
+	// Two-digit years, after every four-digit layout and day-first only, so
+	// "01.02.24" is 1 February 2024. Go maps 69–99 to 1900s and 00–68 to 2000s.
+	"02.01.06", // dd.mm.yy
+	"02/01/06", // dd/mm/yy
+	"02-01-06", // dd-mm-yy
+	"01/02/06", // mm/dd/yy (US format)
+}
+
+// DateTimeFormats lists the supported timestamp formats. Only the date as
+// written is kept, so trips stay whole days.
+var DateTimeFormats = []string{
+	time.RFC3339,          // 2024-03-01T14:30:00Z
+	"2006-01-02T15:04:05", // 2024-03-01T14:30:00
+	"2006-01-02 15:04:05", // 2024-03-01 14:30:00
+	"2006-01-02T15:04",    // 2024-03-01T14:30
+	"2006-01-02 15:04",    // 2024-03-01 14:30
+}
+
+// Rounding selects the date a timestamp with a time of day falls on
+type Rounding string
+
+const (
+	// Floor keeps the date as written, whatever the time (the default)
+	Floor Rounding = "floor"
+	// Ceil moves any time after midnight to the next day
+	Ceil Rounding = "ceil"
+	// Round moves times from noon onwards to the next day
+	Round Rounding = "round"
+)
+
+// RoundToDay returns midnight UTC on the date t falls on under rounding,
+// in t's own zone. A time of exactly midnight is that day in every mode.
+func RoundToDay(t time.Time, rounding Rounding) time.Time {
+	day := TruncateToDay(t)
+	sinceMidnight := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+	switch {
+	case rounding == Ceil && sinceMidnight > 0,
+		rounding == Round && sinceMidnight >= 12*time.Hour:
+		return day.AddDate(0, 0, 1)
+	}
+	return day
+}
+
+// ParseTimestamp parses a timestamp in one of DateTimeFormats, keeping its
+// time of day and zone
+func ParseTimestamp(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, format := range DateTimeFormats {
+		if t, err := time.Parse(format, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unable to parse timestamp: %s", value)
+}
+
+// ParseDate attempts to parse a date string with multiple formats
+func ParseDate(dateStr string) (time.Time, error) {
+	dateStr = strings.TrimSpace(dateStr)
+
+	for _, format := range DateFormats {
+		if t, err := time.Parse(format, dateStr); err == nil {
+			return t, nil
+		}
+	}
+
+	// Drop the time of day, taking the calendar date in the timestamp's own
+	// zone, so a same-day departure and return is still one day
+	if t, err := ParseTimestamp(dateStr); err == nil {
+		return TruncateToDay(t), nil
+	}
+
+	return time.Time{}, fmt.Errorf("unable to parse date: %s", dateStr)
+}
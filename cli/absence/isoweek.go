@@ -0,0 +1,46 @@
+package absence
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// isoWeekPattern matches ISO 8601 week values such as 2024-W10 or 2024W10
+var isoWeekPattern = regexp.MustCompile(`^(\d{4})-?[Ww](\d{2})$`)
+
+// ParseISOWeek returns the Monday and Sunday of an ISO week value
+func ParseISOWeek(value string) (time.Time, time.Time, error) {
+	match := isoWeekPattern.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("unable to parse ISO week: %s", value)
+	}
+	year, _ := strconv.Atoi(match[1])
+	week, _ := strconv.Atoi(match[2])
+
+	// 4 January is always in week 1, so week 1 starts on the Monday on or
+	// before it
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC)
+	monday := jan4.AddDate(0, 0, -(int(jan4.Weekday())+6)%7+(week-1)*7)
+
+	// Rejects week 0 and week 53 in years that only have 52 weeks
+	if isoYear, isoWeek := monday.ISOWeek(); isoYear != year || isoWeek != week {
+		return time.Time{}, time.Time{}, fmt.Errorf("%d has no ISO week %d", year, week)
+	}
+
+	return monday, monday.AddDate(0, 0, 6), nil
+}
+
+// ParseDateOrWeek parses a date, or an ISO week as the range from its
+// Monday to its Sunday; for a plain date first and last are the same day
+func ParseDateOrWeek(value string) (first, last time.Time, week bool, err error) {
+	if date, err := ParseDate(value); err == nil {
+		return date, date, false, nil
+	}
+	if first, last, err := ParseISOWeek(value); err == nil {
+		return first, last, true, nil
+	}
+	return time.Time{}, time.Time{}, false, fmt.Errorf("unable to parse date: %s", strings.TrimSpace(value))
+}
@@ -0,0 +1,79 @@
+package absence
+
+import (
+	"strings"
+	"time"
+)
+
+// monthNames maps each supported locale's month names and abbreviations,
+// lower-cased, to the month. English needs no entry: ParseDate reads it.
+var monthNames = map[string]map[string]time.Month{
+	"de": {
+		"januar": time.January, "jan": time.January, "jänner": time.January,
+		"februar": time.February, "feb": time.February,
+		"märz": time.March, "mär": time.March, "mrz": time.March, "maerz": time.March,
+		"april": time.April, "apr": time.April,
+		"mai":  time.May,
+		"juni": time.June, "jun": time.June,
+		"juli": time.July, "jul": time.July,
+		"august": time.August, "aug": time.August,
+		"september": time.September, "sep": time.September, "sept": time.September,
+		"oktober": time.October, "okt": time.October,
+		"november": time.November, "nov": time.November,
+		"dezember": time.December, "dez": time.December,
+	},
+	"fr": {
+		"janvier": time.January, "janv": time.January,
+		"février": time.February, "fevrier": time.February, "févr": time.February, "fevr": time.February,
+		"mars":  time.March,
+		"avril": time.April, "avr": time.April,
+		"mai":     time.May,
+		"juin":    time.June,
+		"juillet": time.July, "juil": time.July,
+		"août": time.August, "aout": time.August,
+		"septembre": time.September, "sept": time.September,
+		"octobre": time.October, "oct": time.October,
+		"novembre": time.November, "nov": time.November,
+		"décembre": time.December, "decembre": time.December, "déc": time.December, "dec": time.December,
+	},
+	"es": {
+		"enero": time.January, "ene": time.January,
+		"febrero": time.February, "feb": time.February,
+		"marzo": time.March, "mar": time.March,
+		"abril": time.April, "abr": time.April,
+		"mayo": time.May, "may": time.May,
+		"junio": time.June, "jun": time.June,
+		"julio": time.July, "jul": time.July,
+		"agosto": time.August, "ago": time.August,
+		"septiembre": time.September, "setiembre": time.September, "sep": time.September, "sept": time.September,
+		"octubre": time.October, "oct": time.October,
+		"noviembre": time.November, "nov": time.November,
+		"diciembre": time.December, "dic": time.December,
+	},
+}
+
+// Locales lists the locales whose month names TranslateMonths knows
+var Locales = []string{"en", "de", "fr", "es"}
+
+// TranslateMonths replaces month names of locale in value with the English
+// names ParseDate understands, so "02 Januar 2006" becomes "02 January 2006".
+// Spanish "de" between the parts is dropped. Other locales, including "en",
+// return value unchanged.
+func TranslateMonths(value, locale string) string {
+	names, ok := monthNames[locale]
+	if !ok {
+		return value
+	}
+
+	var words []string
+	for _, word := range strings.Fields(value) {
+		lower := strings.ToLower(strings.TrimSuffix(word, "."))
+		if month, ok := names[lower]; ok {
+			word = month.String()
+		} else if locale == "es" && lower == "de" {
+			continue
+		}
+		words = append(words, word)
+	}
+	return strings.Join(words, " ")
+}
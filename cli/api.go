@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"stay-within/absence"
+)
+
+// apiPage is one page of a paginated trips API response. Records are read
+// from "trips" or "data", and "next" is either the URL of the next page or
+// a cursor to pass back as ?cursor=; it is empty or null on the last page.
+type apiPage struct {
+	Trips []map[string]any `json:"trips"`
+	Data  []map[string]any `json:"data"`
+	Next  string           `json:"next"`
+}
+
+// readTripsFromAPI fetches every page of trips from apiURL, sending the
+// --api-token as a bearer token. Records are mapped by tripsFromRecords.
+func readTripsFromAPI(apiURL string, config Config) ([]Trip, int, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	var trips []Trip
+	skipped := 0
+	seen := map[string]bool{}
+
+	for pageURL := apiURL; pageURL != ""; {
+		// Stop rather than loop forever if the API hands back a page twice
+		if seen[pageURL] {
+			return nil, 0, fmt.Errorf("API pagination loops back to %s", pageURL)
+		}
+		seen[pageURL] = true
+
+		page, err := fetchAPIPage(client, pageURL, config.APIToken)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		pageTrips, pageSkipped, err := tripsFromRecords(append(page.Trips, page.Data...), config)
+		if err != nil {
+			return nil, 0, err
+		}
+		trips = append(trips, pageTrips...)
+		skipped += pageSkipped
+
+		pageURL, err = nextPageURL(apiURL, page.Next)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return trips, skipped, nil
+}
+
+// tripsFromRecords maps JSON trip records like CSV rows: the start and end
+// fields are "start" and "end" unless --start-column, --end-column or
+// --columns name others, and records without a valid start are skipped
+func tripsFromRecords(records []map[string]any, config Config) ([]Trip, int, error) {
+	startField, endField := "start", "end"
+	if config.StartColumn != "" {
+		startField = config.StartColumn
+	}
+	if config.EndColumn != "" {
+		endField = config.EndColumn
+	}
+
+	var trips []Trip
+	skipped := 0
+
+	for _, record := range records {
+		for _, field := range []string{startField, endField} {
+			if value, ok := record[field].(string); ok {
+				record[field] = localizeDate(value, config)
+			}
+		}
+		startDate, endDate, week, err := absence.ParseDateOrWeek(apiField(record, startField))
+		if err != nil {
+			if config.Strict {
+				return nil, 0, fmt.Errorf("invalid %s date %q in record %v", startField, apiField(record, startField), record)
+			}
+			skipped++
+			continue
+		}
+
+		// A record without an end is a one-day trip
+		ongoing := false
+		if _, present := record[endField]; present {
+			end := apiField(record, endField)
+			if isOngoingEnd(end) {
+				endDate, ongoing = ongoingEnd(startDate, config), true
+			} else if _, last, endWeek, err := absence.ParseDateOrWeek(end); err == nil {
+				endDate = last
+				week = week || endWeek
+			} else {
+				if config.Strict {
+					return nil, 0, fmt.Errorf("invalid %s date %q in record %v", endField, end, record)
+				}
+				fmt.Fprintf(os.Stderr, "Warning: invalid %s date %q in record %v, skipping it\n", endField, end, record)
+				skipped++
+				continue
+			}
+		}
+
+		if endDate.Before(startDate) && config.AutoFixSwapped {
+			startDate, endDate = swappedDates(apiField(record, startField), apiField(record, endField))
+			fmt.Fprintf(os.Stderr, "Note: trip %s to %s ends before it starts, swapped start and end\n",
+				apiField(record, startField), apiField(record, endField))
+		} else if endDate.Before(startDate) {
+			if config.Strict {
+				return nil, 0, fmt.Errorf("trip %s to %s ends before it starts",
+					apiField(record, startField), apiField(record, endField))
+			}
+			fmt.Fprintf(os.Stderr, "Warning: trip %s to %s ends before it starts, skipping it\n",
+				apiField(record, startField), apiField(record, endField))
+			skipped++
+			continue
+		}
+
+		trip := Trip{
+			Start:   startDate,
+			End:     endDate,
+			ByWeek:  week,
+			Ongoing: ongoing,
+		}
+		trip.Days = absence.TripDays(trip, config.DayCount)
+		if config.PersonColumn != "" {
+			trip.Person = apiField(record, config.PersonColumn)
+		}
+		if config.DestColumn != "" {
+			trip.Destination = apiField(record, config.DestColumn)
+		} else if dest := apiField(record, "destination"); dest != "" {
+			trip.Destination = dest
+		} else {
+			trip.Destination = apiField(record, "country")
+		}
+		trips = append(trips, trip)
+	}
+
+	return trips, skipped, nil
+}
+
+// fetchAPIPage requests and decodes one page of the trips API
+func fetchAPIPage(client *http.Client, pageURL, token string) (apiPage, error) {
+	var page apiPage
+
+	req, err := http.NewRequest(http.MethodGet, pageURL, nil)
+	if err != nil {
+		return page, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return page, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return page, fmt.Errorf("API returned %s for %s", resp.Status, pageURL)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return page, fmt.Errorf("decoding API response: %w", err)
+	}
+	return page, nil
+}
+
+// nextPageURL resolves the "next" value of a page against the base API URL:
+// a URL (absolute or relative) is followed as-is, anything else is sent as
+// the cursor query parameter. A URL on another scheme or host is refused,
+// so the --api-token is never sent anywhere but the --api-url's server.
+func nextPageURL(apiURL, next string) (string, error) {
+	if next == "" {
+		return "", nil
+	}
+
+	base, err := url.Parse(apiURL)
+	if err != nil {
+		return "", err
+	}
+
+	if strings.Contains(next, "/") || strings.Contains(next, "?") {
+		ref, err := url.Parse(next)
+		if err != nil {
+			return "", fmt.Errorf("invalid next page URL %q: %w", next, err)
+		}
+		resolved := base.ResolveReference(ref)
+		if resolved.Scheme != base.Scheme || resolved.Host != base.Host {
+			return "", fmt.Errorf("next page URL %q is not on %s://%s", next, base.Scheme, base.Host)
+		}
+		return resolved.String(), nil
+	}
+
+	query := base.Query()
+	query.Set("cursor", next)
+	base.RawQuery = query.Encode()
+	return base.String(), nil
+}
+
+// apiField returns a record field as a trimmed string, or "" if it is
+// missing or not a string
+func apiField(record map[string]any, name string) string {
+	value, _ := record[name].(string)
+	return strings.TrimSpace(value)
+}
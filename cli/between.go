@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"stay-within/absence"
+)
+
+// windowSnapshot is the rolling-window standing on one reference date
+type windowSnapshot struct {
+	Date             time.Time
+	WindowStart      time.Time
+	TotalDaysOutside int
+	DaysRemaining    int
+	Status           string
+}
+
+// windowChange is a trip that entered or left the window between two dates,
+// with the days it contributed to the window it was part of
+type windowChange struct {
+	Trip Trip
+	Days int
+}
+
+// takeSnapshot computes the rolling-window standing on date
+func takeSnapshot(trips []Trip, date time.Time, config Config) windowSnapshot {
+	start := absence.WindowStart(date, config.Config)
+	total := absence.CalculateDaysInWindow(trips, start, date, config.DayCount)
+
+	return windowSnapshot{
+		Date:             date,
+		WindowStart:      start,
+		TotalDaysOutside: total,
+		DaysRemaining:    config.AbsenceLimit - total,
+		Status:           absence.Status(config.AbsenceLimit-total, config.Config),
+	}
+}
+
+// compareWindows returns the trips that are in the to window but not the
+// from window (entered), and those in the from window but not the to
+// window (left)
+func compareWindows(trips []Trip, from, to windowSnapshot, config Config) (entered, left []windowChange) {
+	for _, trip := range trips {
+		before := absence.CalculateDaysInWindow([]Trip{trip}, from.WindowStart, from.Date, config.DayCount)
+		after := absence.CalculateDaysInWindow([]Trip{trip}, to.WindowStart, to.Date, config.DayCount)
+
+		if before == 0 && after > 0 {
+			entered = append(entered, windowChange{Trip: trip, Days: after})
+		} else if before > 0 && after == 0 {
+			left = append(left, windowChange{Trip: trip, Days: before})
+		}
+	}
+	return entered, left
+}
+
+// parseBetween parses the --between value into its two reference dates
+func parseBetween(config Config) (time.Time, time.Time) {
+	parts := strings.Split(config.Between, ",")
+	if len(parts) != 2 {
+		fmt.Fprintf(os.Stderr, "Error: --between needs two dates, e.g. --between 01.01.2025 01.07.2025\n")
+		os.Exit(1)
+	}
+
+	from, err1 := absence.ParseDate(parts[0])
+	to, err2 := absence.ParseDate(parts[1])
+	if err1 != nil || err2 != nil {
+		fmt.Fprintf(os.Stderr, "Error: Invalid date format for --between parameter. Use format: dd.mm.yyyy\n")
+		os.Exit(1)
+	}
+	return from, to
+}
+
+// displayBetween displays how the status changed between the two --between dates
+func displayBetween(trips []Trip, config Config) {
+	fromDate, toDate := parseBetween(config)
+	from := takeSnapshot(trips, fromDate, config)
+	to := takeSnapshot(trips, toDate, config)
+	entered, left := compareWindows(trips, from, to, config)
+
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", config.Width))
+	fmt.Printf("CHANGE BETWEEN %s AND %s\n", from.Date.Format(config.DateFormat), to.Date.Format(config.DateFormat))
+	fmt.Println(strings.Repeat("=", config.Width))
+	fmt.Println()
+
+	fmt.Println(strings.Repeat("-", config.Width))
+	fmt.Printf("%-24s | %-12s | %-12s | %s\n", "", from.Date.Format(config.DateFormat), to.Date.Format(config.DateFormat), "Change")
+	fmt.Println(strings.Repeat("-", config.Width))
+	fmt.Printf("%-24s | %12d | %12d | %+8d\n", fmt.Sprintf("Days outside (%s)", windowAbbrev(config)),
+		from.TotalDaysOutside, to.TotalDaysOutside, to.TotalDaysOutside-from.TotalDaysOutside)
+	fmt.Printf("%-24s | %12d | %12d | %+8d\n", fmt.Sprintf("Days remaining (of %d)", config.AbsenceLimit),
+		from.DaysRemaining, to.DaysRemaining, to.DaysRemaining-from.DaysRemaining)
+	fmt.Printf("%-24s | %12s | %12s\n", "Status", from.Status, to.Status)
+	fmt.Println(strings.Repeat("-", config.Width))
+
+	printChanges := func(title string, changes []windowChange) {
+		fmt.Printf("\n%s:\n", title)
+		if len(changes) == 0 {
+			fmt.Println("  (none)")
+		}
+		for _, change := range changes {
+			fmt.Printf("  %s to %s  (%d days in window)\n",
+				change.Trip.Start.Format(config.DateFormat), change.Trip.End.Format(config.DateFormat), change.Days)
+		}
+	}
+	printChanges("Trips that entered the window", entered)
+	printChanges("Trips that left the window", left)
+
+	fmt.Println()
+}
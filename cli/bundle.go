@@ -0,0 +1,139 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// runBundle runs the analysis of trips while saving a reproducible copy of
+// it to the --bundle directory: the input trips, as read and before any
+// filtering, as normalized CSV, the effective flags as JSON and the output
+// exactly as printed
+func runBundle(input, trips []Trip, config Config) {
+	if err := os.MkdirAll(config.Bundle, 0o755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating bundle: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := writeBundleInput(input, config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing bundle: %v\n", err)
+		os.Exit(1)
+	}
+
+	outputName := "output.txt"
+	if config.JsonOutput || config.MonthlyJSON {
+		outputName = "output.json"
+	}
+	outputFile, err := os.Create(filepath.Join(config.Bundle, outputName))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing bundle: %v\n", err)
+		os.Exit(1)
+	}
+	defer outputFile.Close()
+
+	// Tee everything the analysis prints into the bundle
+	stdout := os.Stdout
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing bundle: %v\n", err)
+		os.Exit(1)
+	}
+	copied := make(chan error)
+	go func() {
+		_, err := io.Copy(io.MultiWriter(stdout, outputFile), reader)
+		copied <- err
+	}()
+
+	// The saved output is plain text even when the terminal shows color
+	config.Color = false
+	os.Stdout = writer
+	runAnalyze(trips, config)
+	os.Stdout = stdout
+	writer.Close()
+
+	if err := <-copied; err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing bundle: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote bundle to %s\n", config.Bundle)
+}
+
+// bundleOmittedFlags are left out of the bundle's config.json: the config
+// and bundle paths themselves, where the trips came from, and how the
+// original input was read, which doesn't apply to the normalized trips.csv
+var bundleOmittedFlags = []string{
+	"config", "bundle", "api-url", "api-token", "interactive",
+	"input-format", "gzip", "delimiter", "comment-char", "locale",
+	"start-column", "end-column", "columns", "person-col",
+}
+
+// flagValues returns every flag's value by name as --config reads them
+// back, with the values given to each repeatable flag in lists. Flags
+// that are empty by default and still empty are left out, which also
+// leaves out func flags such as --validate, whose value is never shown.
+func flagValues(fs *flag.FlagSet, lists map[string][]string) map[string]any {
+	values := map[string]any{}
+	fs.VisitAll(func(f *flag.Flag) {
+		if list, ok := lists[f.Name]; ok {
+			if len(list) > 0 {
+				values[f.Name] = list
+			}
+			return
+		}
+		if value := f.Value.String(); value != "" || f.DefValue != "" {
+			values[f.Name] = value
+		}
+	})
+	return values
+}
+
+// writeBundleInput writes the trips as read, normalized, and the effective
+// flag values to the bundle directory, so that running on trips.csv with
+// --config config.json gives the same result
+func writeBundleInput(trips []Trip, config Config) error {
+	tripsFile, err := os.Create(filepath.Join(config.Bundle, "trips.csv"))
+	if err != nil {
+		return err
+	}
+	defer tripsFile.Close()
+	if err := writeNormalizedCSV(tripsFile, trips); err != nil {
+		return err
+	}
+
+	values := maps.Clone(config.FlagValues)
+	for _, name := range bundleOmittedFlags {
+		delete(values, name)
+	}
+	if config.PersonColumn != "" {
+		values["person-col"] = "Person"
+	}
+
+	// Pin the target date so the bundle reproduces the same result later.
+	// A --preset's window and limit aren't in the flags, and
+	// --first-day-of-week is a func flag with no value to show.
+	values["date"] = resolveTargetDate(config).Format("02.01.2006")
+	values["window"] = strconv.Itoa(config.WindowMonths)
+	values["window-days"] = strconv.Itoa(config.WindowDays)
+	values["limit"] = strconv.Itoa(config.AbsenceLimit)
+	values["first-day-of-week"] = strings.ToLower(config.FirstDayOfWeek.String())
+
+	configFile, err := os.Create(filepath.Join(config.Bundle, "config.json"))
+	if err != nil {
+		return err
+	}
+	defer configFile.Close()
+	if err := writeJSON(configFile, values); err != nil {
+		return err
+	}
+
+	if err := tripsFile.Close(); err != nil {
+		return err
+	}
+	return configFile.Close()
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"stay-within/absence"
+)
+
+// calendarMonthWidth is the width of one month in the --calendar view:
+// seven days of four columns each
+const calendarMonthWidth = 7 * 4
+
+// abroadDays returns the days of trips that count as days abroad, so
+// excluded trips and uncounted travel days are left out
+func abroadDays(trips []Trip, config Config) map[time.Time]bool {
+	days := map[time.Time]bool{}
+	for _, trip := range trips {
+		first, last := absence.CountedRange(trip, config.DayCount)
+		for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+			days[day] = true
+		}
+	}
+	return days
+}
+
+// calendarRange returns the first and last month the --calendar view
+// shows: the months of its year, or for "all" the months from the first
+// trip's start to the last trip's end
+func calendarRange(trips []Trip, config Config) (first, last time.Time) {
+	if config.Calendar != "all" {
+		year, _ := strconv.Atoi(config.Calendar)
+		return time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC), time.Date(year, time.December, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	end := trips[0].End
+	for _, trip := range trips {
+		end = maxTime(end, trip.End)
+	}
+	start := firstTripStart(trips)
+	return time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC), time.Date(end.Year(), end.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// calendarMonth renders month as lines calendarMonthWidth columns wide:
+// a title with the days abroad that month, the weekday names from
+// --first-day-of-week and the weeks, with days abroad marked "*"
+func calendarMonth(month time.Time, abroad map[time.Time]bool, config Config) []string {
+	var days []string
+	total := 0
+	offset := (int(month.Weekday()) - int(config.FirstDayOfWeek) + 7) % 7
+	for range offset {
+		days = append(days, "    ")
+	}
+	for day := month; day.Month() == month.Month(); day = day.AddDate(0, 0, 1) {
+		if abroad[day] {
+			total++
+			days = append(days, colorize(fmt.Sprintf("%4s", fmt.Sprintf("*%d", day.Day())), "caution", config))
+		} else {
+			days = append(days, fmt.Sprintf("%4d", day.Day()))
+		}
+	}
+	for len(days)%7 != 0 {
+		days = append(days, "    ")
+	}
+
+	title := month.Format("January 2006")
+	if total > 0 {
+		title += fmt.Sprintf(" (%d)", total)
+	}
+	padding := (calendarMonthWidth - len(title)) / 2
+	lines := []string{fmt.Sprintf("%-*s", calendarMonthWidth, strings.Repeat(" ", padding)+title)}
+
+	var names strings.Builder
+	for i := range 7 {
+		names.WriteString("  " + time.Weekday((int(config.FirstDayOfWeek) + i) % 7).String()[:2])
+	}
+	lines = append(lines, names.String())
+
+	for week := 0; week < len(days); week += 7 {
+		lines = append(lines, strings.Join(days[week:week+7], ""))
+	}
+	return lines
+}
+
+// displayCalendar prints a month calendar of the --calendar year or the
+// whole trip history, with the days abroad marked, several months abreast
+func displayCalendar(trips []Trip, config Config) {
+	first, last := calendarRange(trips, config)
+	abroad := abroadDays(trips, config)
+	perRow := max(1, min(3, (config.Width+2)/(calendarMonthWidth+2)))
+
+	title := "all trips"
+	if config.Calendar != "all" {
+		title = config.Calendar
+	}
+	fmt.Println(strings.Repeat("=", config.Width))
+	fmt.Printf("CALENDAR - Days abroad, %s\n", title)
+	fmt.Println(strings.Repeat("=", config.Width))
+
+	for month := first; !month.After(last); month = month.AddDate(0, perRow, 0) {
+		var months [][]string
+		for m := month; len(months) < perRow && !m.After(last); m = m.AddDate(0, 1, 0) {
+			months = append(months, calendarMonth(m, abroad, config))
+		}
+
+		fmt.Println()
+		for line := 0; ; line++ {
+			var row []string
+			printed := false
+			for _, lines := range months {
+				if line < len(lines) {
+					row = append(row, lines[line])
+					printed = true
+				} else {
+					row = append(row, strings.Repeat(" ", calendarMonthWidth))
+				}
+			}
+			if !printed {
+				break
+			}
+			fmt.Println(strings.TrimRight(strings.Join(row, "  "), " "))
+		}
+	}
+
+	total := 0
+	for day := range abroad {
+		if !day.Before(first) && day.Before(last.AddDate(0, 1, 0)) {
+			total++
+		}
+	}
+	fmt.Printf("\n* marks a day abroad, with each month's total in brackets: %d %s in all.\n\n",
+		total, plural(total, "day", "days"))
+}
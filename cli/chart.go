@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"stay-within/absence"
+)
+
+// displayChart draws each trip's rolling-window total as a horizontal bar,
+// scaled so the limit falls on the same column in every row and marked "|"
+func displayChart(rows []absence.TripResult, config Config) {
+	if len(rows) == 0 {
+		return
+	}
+
+	// Room for "dd.mm.yyyy  " before the bar and " nnnn" after it
+	label := len(rows[0].Trip.End.Format(config.DateFormat))
+	barWidth := max(config.Width-label-8, 10)
+
+	scale := config.AbsenceLimit
+	for _, row := range rows {
+		scale = max(scale, row.DaysInWindow)
+	}
+	columns := func(days int) int {
+		return days * barWidth / scale
+	}
+	limitColumn := min(columns(config.AbsenceLimit), barWidth-1)
+
+	fmt.Printf("Rolling total at each trip end (| marks the %d-day limit):\n", config.AbsenceLimit)
+	for _, row := range rows {
+		filled := columns(max(row.DaysInWindow, 0))
+
+		var bar strings.Builder
+		for column := range barWidth {
+			switch {
+			case column < filled && config.ASCII:
+				bar.WriteString("#")
+			case column < filled:
+				bar.WriteString("█")
+			case column == limitColumn:
+				bar.WriteString("|")
+			default:
+				bar.WriteString(" ")
+			}
+		}
+
+		status := absence.Status(row.DaysRemaining, config.Config)
+		fmt.Printf("%-*s  %s %4d\n", label, row.Trip.End.Format(config.DateFormat),
+			colorize(bar.String(), status, config), row.DaysInWindow)
+	}
+	fmt.Println()
+}
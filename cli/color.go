@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// ANSI escape sequences for the status colors
+const (
+	ansiRed    = "\033[31m"
+	ansiYellow = "\033[33m"
+	ansiGreen  = "\033[32m"
+	ansiReset  = "\033[0m"
+)
+
+// statusColors maps each absence status to the color it is shown in
+var statusColors = map[string]string{
+	"exceeded": ansiRed,
+	"caution":  ansiYellow,
+	"ok":       ansiGreen,
+}
+
+// colorize wraps text in the color for status, or returns it unchanged when
+// color is off
+func colorize(text, status string, config Config) string {
+	color, ok := statusColors[status]
+	if !config.Color || !ok {
+		return text
+	}
+	return color + text + ansiReset
+}
+
+// stdoutIsTerminal reports whether stdout is a terminal rather than a file or pipe
+func stdoutIsTerminal() bool {
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// warnMark is the marker before warnings, "[WARN]" with --ascii
+func warnMark(config Config) string {
+	if config.ASCII {
+		return "[WARN]"
+	}
+	return "⚠️ "
+}
+
+// okMark is the marker before an all-clear, "[OK]" with --ascii
+func okMark(config Config) string {
+	if config.ASCII {
+		return "[OK]"
+	}
+	return "✓"
+}
+
+// issueMark is the marker before each problem in a list, "[!]" with --ascii
+func issueMark(config Config) string {
+	if config.ASCII {
+		return "[!]"
+	}
+	return "⚠️ "
+}
+
+// localeIsUTF8 reports whether the locale environment, if set, selects a
+// UTF-8 character set. The first of LC_ALL, LC_CTYPE and LANG that is set
+// decides; with none set the terminal is assumed to cope.
+func localeIsUTF8() bool {
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			value = strings.ToLower(value)
+			return strings.Contains(value, "utf-8") || strings.Contains(value, "utf8")
+		}
+	}
+	return true
+}
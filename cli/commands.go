@@ -0,0 +1,435 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"stay-within/absence"
+)
+
+// command describes a subcommand: its help text and the flags it accepts on
+// top of the shared --date, --window, --limit and --csv-strict flags
+type command struct {
+	Name     string
+	Summary  string
+	Args     string
+	Flags    func(fs *flag.FlagSet, config *Config)
+	Options  [][2]string
+	Examples []string
+}
+
+// commands lists the available subcommands; the first one is the default
+var commands = []*command{
+	{
+		Name:    "analyze",
+		Summary: "Per-trip analysis and current status (default)",
+		Args:    "<csv_file>... [options]",
+		Flags: func(fs *flag.FlagSet, config *Config) {
+			fs.BoolVar(&config.JsonOutput, "json", false, "Output results as JSON")
+			fs.BoolVar(&config.YAMLOutput, "yaml", false, "Output results as YAML")
+			fs.BoolVar(&config.CSVOut, "csv-out", false, "Output the per-trip analysis as CSV")
+			fs.BoolVar(&config.Monthly, "monthly", false, "Show the rolling total at every month-end up to the target date")
+			fs.BoolVar(&config.MonthlyJSON, "monthly-json", false, "Output the status at every month-end as a JSON array")
+			fs.BoolVar(&config.ExcludeAnchorTrip, "exclude-anchor-trip", false, "Exclude each trip's own days from its per-trip window total")
+			fs.StringVar((*string)(&config.Anchor), "anchor", "end", "End each trip's window on its end or start date")
+			fs.StringVar(&config.ApplyDate, "apply-date", "", "Project the status to a planned application date")
+			fs.BoolVar(&config.DataSummary, "data-summary", false, "Print a one-line data-quality summary")
+			fs.StringVar(&config.StatusBasis, "status-basis", "", "Judge the status on the current, worst or stricter window")
+			fs.BoolVar(&config.ActiveOnly, "active-only", false, "List only the trips overlapping the current window in the per-trip table")
+			fs.StringVar(&config.Sort, "sort", "end", "Order the per-trip table by end, start, days or remaining")
+			fs.BoolVar(&config.ShowFlags, "flags", false, "Show the flag emoji next to each trip's destination")
+			fs.BoolVar(&config.ShowHeadroom, "headroom", false, "Show days of headroom and the year-end projection at the current pace")
+			fs.BoolVar(&config.ShowAverage, "average", false, "Show the average days abroad per month over the window")
+			fs.StringVar(&config.Between, "between", "", "Compare the status on two dates")
+			fs.BoolVar(&config.Chart, "chart", false, "Draw the per-trip window totals as a bar chart")
+			fs.BoolVar(&config.ShowMaxStay, "max-stay", false, "Add the date to be back by to the max continuous trip in the status")
+			fs.BoolVar(&config.GroupByYear, "group-by-year", false, "Show the days abroad in each calendar year")
+			fs.BoolVar(&config.Forward, "forward", false, "Also count the days outside in the window looking forward from the target date")
+			fs.StringVar(&config.Calendar, "calendar", "", "Print a month calendar of the days abroad in a year, or all")
+			fs.Func("first-day-of-week", "Start --calendar weeks on monday (default) or sunday", func(value string) error {
+				switch strings.ToLower(value) {
+				case "monday":
+					config.FirstDayOfWeek = time.Monday
+				case "sunday":
+					config.FirstDayOfWeek = time.Sunday
+				default:
+					return fmt.Errorf("use monday or sunday")
+				}
+				return nil
+			})
+			fs.BoolVar(&config.TaxYear, "tax-year", false, "Report days outside per UK tax year (6 April to 5 April) against the limit")
+			fs.BoolVar(&config.Quiet, "quiet", false, "Print only the days outside, days remaining and status")
+			fs.BoolVar(&config.Verbose, "verbose", false, "List the days each trip contributes to the current window, and each trip's window dates")
+			fs.BoolVar(&config.Explain, "explain", false, "Explain step by step how the current status was calculated")
+			fs.IntVar(&config.SafeTravelDays, "plan-days", 0, "Find the earliest safe start date for a trip of this many days")
+			fs.IntVar(&config.MaxSingle, "max-single", 0, "Flag any single trip longer than this many days")
+			fs.StringVar(&config.Serve, "serve", "", "Serve the JSON status over HTTP on this address")
+			fs.StringVar(&config.Bundle, "bundle", "", "Write the input, config and output to a directory")
+			fs.StringVar(&config.XLSXOut, "xlsx-out", "", "Also write the per-trip analysis to an Excel .xlsx file")
+			fs.StringVar(&config.ICalOut, "ical-out", "", "Also write the trips to an iCalendar .ics file")
+			fs.BoolFunc("validate", "Run the validate checks instead of the analysis", func(string) error {
+				config.Command = "validate"
+				return nil
+			})
+			fs.IntVar(&config.Horizon, "horizon", 365, "With --validate, fail trips ending more than this many days after the target date")
+		},
+		Options: [][2]string{
+			{"--json", "Output results as JSON"},
+			{"--yaml", "Output the JSON document as YAML instead"},
+			{"--csv-out", "Output the per-trip analysis as CSV, with a status row"},
+			{"--monthly", "Also show the rolling total at the end of every month"},
+			{"--monthly-json", "Output the status at every month-end as a JSON array"},
+			{"--anchor <end|start>", "End each trip's window on its end date (default) or start date"},
+			{"--exclude-anchor-trip", "Count only absences before each trip in the per-trip table"},
+			{"--apply-date <date>", "Project your status to a planned application date"},
+			{"--data-summary", "Print counts of parsed, skipped, overlapping and future trips"},
+			{"--status-basis <b>", "current, worst (historical peak) or stricter of the two"},
+			{"--active-only", "List only the trips overlapping the current window in the per-trip table"},
+			{"--sort <by>", "Order the per-trip table by end (default), start, days or remaining"},
+			{"--flags", "Show the flag emoji next to each trip's destination"},
+			{"--headroom", "Show your headroom and where your current pace leads by year-end"},
+			{"--average", "Show the average days abroad per month over the window"},
+			{"--between <d1> <d2>", "Show how the status and window changed between two dates"},
+			{"--chart", "Draw each trip's window total as a bar against the limit"},
+			{"--max-stay", "Add the date to be back by to the status's max continuous trip"},
+			{"--group-by-year", "Also show the days abroad in each calendar year (byYear in JSON)"},
+			{"--forward", "Also count the days outside in the window looking ahead from the target date"},
+			{"--calendar <year>", "Instead, print a month calendar of the days abroad in a year, or all"},
+			{"--first-day-of-week <d>", "Start --calendar weeks on monday (default) or sunday"},
+			{"--tax-year", "Report days outside per UK tax year (6 April to 5 April) instead"},
+			{"--quiet", "Print only the days outside, days remaining and status line"},
+			{"--verbose", "List the days each trip contributes to the current window, and each trip's window dates"},
+			{"--explain", "Explain step by step how the current status was calculated"},
+			{"--plan-days <n>", "Show the earliest date an n-day trip can start without a breach"},
+			{"--max-single <n>", "Flag any single trip longer than n days (longTrips in JSON)"},
+			{"--serve <addr>", "Serve the JSON status at http://<addr>/status (e.g. :8080)"},
+			{"--bundle <dir>", "Also save the input, effective config and output to <dir>"},
+			{"--xlsx-out <path>", "Also write the per-trip analysis to an Excel .xlsx file"},
+			{"--ical-out <path>", "Also write the trips, and when you are back within the limit, to a .ics file"},
+			{"--validate", "Run the validate command's checks instead of the analysis"},
+			{"--horizon <days>", "With --validate, fail trips ending over this many days ahead (default: 365)"},
+		},
+		Examples: []string{
+			"trips.csv",
+			"trips.csv --date 01.01.2026",
+			"2023.csv 2024.csv --window 12",
+			"trips.csv --window 24 --limit 365",
+			"trips.csv --apply-date 01.06.2026",
+			"trips.csv --between 01.01.2025 01.07.2025",
+			"trips.csv --xlsx-out analysis.xlsx",
+			"trips.csv --serve :8080",
+			"analyze trips.csv --date 01.01.2026 --window 6 --limit 90",
+		},
+	},
+	{
+		Name:    "plan",
+		Summary: "Check whether a planned trip keeps you within the limit",
+		Args:    "<csv_file> --start <date> (--end <date> | --days <n>) [options]",
+		Flags: func(fs *flag.FlagSet, config *Config) {
+			fs.StringVar(&config.PlanStart, "start", "", "Start date of the planned trip")
+			fs.StringVar(&config.PlanEnd, "end", "", "End date of the planned trip")
+			fs.IntVar(&config.PlanDays, "days", 0, "Length of the planned trip in days")
+			fs.BoolVar(&config.JsonOutput, "json", false, "Output results as JSON")
+		},
+		Options: [][2]string{
+			{"--start <date>", "Start date of the planned trip"},
+			{"--end <date>", "End date of the planned trip"},
+			{"--days <n>", "Length of the planned trip in days (instead of --end)"},
+			{"--json", "Output results as JSON"},
+		},
+		Examples: []string{
+			"plan trips.csv --start 01.06.2026 --end 15.06.2026",
+			"plan trips.csv --start 01.06.2026 --days 30 --window 6 --limit 90",
+		},
+	},
+	{
+		Name:    "validate",
+		Summary: "Check a trips file for invalid rows, overlaps, order and far-future trips",
+		Args:    "<csv_file>... [options]",
+		Flags: func(fs *flag.FlagSet, config *Config) {
+			fs.IntVar(&config.Horizon, "horizon", 365, "Fail trips ending more than this many days after the target date")
+		},
+		Options: [][2]string{
+			{"--horizon <days>", "Fail trips ending more than this many days after today (default: 365)"},
+		},
+		Examples: []string{
+			"validate trips.csv",
+			"validate trips.csv --csv-strict",
+			"validate trips.csv --horizon 0",
+		},
+	},
+	{
+		Name:    "export",
+		Summary: "Write the analysis in a machine-readable format",
+		Args:    "<csv_file>... [options]",
+		Flags: func(fs *flag.FlagSet, config *Config) {
+			fs.StringVar(&config.ExportFormat, "format", "json", "Output format (json)")
+		},
+		Options: [][2]string{
+			{"--format <name>", "Output format: json (default: json)"},
+		},
+		Examples: []string{
+			"export trips.csv --format json > analysis.json",
+		},
+	},
+	{
+		Name:    "normalize",
+		Summary: "Rewrite a trips file as clean, sorted dd.mm.yyyy CSV",
+		Args:    "<csv_file>... [options]",
+		Flags:   func(fs *flag.FlagSet, config *Config) {},
+		Examples: []string{
+			"normalize messy.csv > trips.csv",
+		},
+	},
+}
+
+// findCommand returns the subcommand with the given name, or nil
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.Name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// printUsage prints help for cmd, listing all subcommands first
+func printUsage(cmd *command) {
+	fmt.Fprintf(os.Stderr, "Usage: %s [command] <csv_file>... [options]\n\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "Commands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-21s %s\n", c.Name, c.Summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nUsage: %s %s %s\n\n", os.Args[0], cmd.Name, cmd.Args)
+	fmt.Fprintf(os.Stderr, "Options:\n")
+	fmt.Fprintf(os.Stderr, "  --config <file>       Option defaults (default: .stay-within.yaml or .json if present)\n")
+	fmt.Fprintf(os.Stderr, "  --date <dd.mm.yyyy>   Use a specific date for calculation instead of today\n")
+	fmt.Fprintf(os.Stderr, "  --from <date>         Ignore trip days before this date (trips are clipped)\n")
+	fmt.Fprintf(os.Stderr, "  --to <date>           Ignore trip days after this date (trips are clipped)\n")
+	fmt.Fprintf(os.Stderr, "  --residence-start <d> Start of the qualifying period; earlier trip days don't count\n")
+	fmt.Fprintf(os.Stderr, "  --add-trip <s:e>      Add a hypothetical trip, e.g. 01.06.2026:15.06.2026 (repeatable)\n")
+	fmt.Fprintf(os.Stderr, "  --exclude-shorter-than <n> List trips under n days but don't count them\n")
+	fmt.Fprintf(os.Stderr, "  --same-day-counts=false    List same-day round trips but don't count them\n")
+	fmt.Fprintf(os.Stderr, "  --window <months>     Rolling window period in months (default: 12)\n")
+	fmt.Fprintf(os.Stderr, "  --window-days <days>  Rolling window period in days, overriding --window\n")
+	fmt.Fprintf(os.Stderr, "  --limit <days>        Maximum allowed absence days in window (default: 180)\n")
+	fmt.Fprintf(os.Stderr, "  --warn-at <days>      Show caution at or below this many days remaining\n")
+	fmt.Fprintf(os.Stderr, "  --preset <name>       Use a well-known rule; --window/--limit still override it:\n")
+	for _, p := range presets {
+		fmt.Fprintf(os.Stderr, "  %-21s   %-12s %s\n", "", p.Name, p.Description)
+	}
+	fmt.Fprintf(os.Stderr, "  --rule <w:limit>      Also check a rule such as 60mo:450[:warn-at], 180d:90 or a preset (repeatable)\n")
+	fmt.Fprintf(os.Stderr, "  --csv-strict          Fail on CSV that violates RFC 4180 instead of tolerating it\n")
+	fmt.Fprintf(os.Stderr, "  --strict              Fail on unparseable or reversed rows instead of skipping\n")
+	fmt.Fprintf(os.Stderr, "  --auto-fix-swapped    Swap the dates of reversed rows instead of skipping them\n")
+	fmt.Fprintf(os.Stderr, "  --input-format <f>    csv, json or xlsx (default: by extension, otherwise csv)\n")
+	fmt.Fprintf(os.Stderr, "  --gzip                Read gzip-compressed CSV (automatic for .gz files)\n")
+	fmt.Fprintf(os.Stderr, "  --delimiter <char>    Field delimiter: ',', '\\t', ';' or '|' (default: detected)\n")
+	fmt.Fprintf(os.Stderr, "  --comment-char <c>    Ignore CSV lines starting with c (default: #; \"\" for none)\n")
+	fmt.Fprintf(os.Stderr, "  --locale <lang>       Also read month names in de, fr or es (default: en)\n")
+	fmt.Fprintf(os.Stderr, "  --tz <zone>           IANA time zone for today and timestamps (default: local)\n")
+	fmt.Fprintf(os.Stderr, "  --rounding <mode>     Date a timestamp falls on: floor (default), ceil or round\n")
+	fmt.Fprintf(os.Stderr, "  --day-count <mode>    inclusive (default), exclusive or departure-only\n")
+	fmt.Fprintf(os.Stderr, "  --return-day-counts=false  Don't count the day of return (same as departure-only)\n")
+	fmt.Fprintf(os.Stderr, "  --count-mode <mode>   abroad: count every trip (default); countries: only --countries\n")
+	fmt.Fprintf(os.Stderr, "  --countries <list>    Comma-separated destinations that count in countries mode\n")
+	fmt.Fprintf(os.Stderr, "  --only-countries <l>  Count only trips to these destinations (comma-separated)\n")
+	fmt.Fprintf(os.Stderr, "  --exclude-countries <l> Don't count trips to these destinations (comma-separated)\n")
+	fmt.Fprintf(os.Stderr, "  --start-column <name> Header name or number of the trip start date column\n")
+	fmt.Fprintf(os.Stderr, "  --end-column <name>   Header name or number of the trip end date column\n")
+	fmt.Fprintf(os.Stderr, "  --columns <map>       Column mapping, e.g. start=departure,end=return,destination=3\n")
+	fmt.Fprintf(os.Stderr, "  --person-col <name>   Column naming who took each trip; analyze each person separately\n")
+	fmt.Fprintf(os.Stderr, "  --midnight=false      Keep the current time of day in the target date and window\n")
+	fmt.Fprintf(os.Stderr, "  --width <columns>     Width of the text report (default: terminal width, up to 90)\n")
+	fmt.Fprintf(os.Stderr, "  --out-date-format <f> Print dates as iso, uk, us or a Go layout (default: 02.01.2006)\n")
+	fmt.Fprintf(os.Stderr, "  --no-color            Print plain text without colors or flag emoji (also NO_COLOR)\n")
+	fmt.Fprintf(os.Stderr, "  --ascii               Print [WARN]/[OK] instead of emoji (default for non-UTF-8 locales)\n")
+	fmt.Fprintf(os.Stderr, "  --api-url <url>       Fetch trips from a paginated JSON API instead of a CSV file\n")
+	fmt.Fprintf(os.Stderr, "  --api-token <token>   Bearer token sent with --api-url requests\n")
+	fmt.Fprintf(os.Stderr, "  --interactive         Enter the trips at prompts instead of reading a CSV file\n")
+	for _, opt := range cmd.Options {
+		fmt.Fprintf(os.Stderr, "  %-21s %s\n", opt[0], opt[1])
+	}
+	fmt.Fprintf(os.Stderr, "\nExamples:\n")
+	for _, example := range cmd.Examples {
+		fmt.Fprintf(os.Stderr, "  %s %s\n", os.Args[0], example)
+	}
+	fmt.Fprintln(os.Stderr)
+}
+
+// runPlan adds the planned trip to the history and shows the status on the
+// day the planned trip ends
+func runPlan(trips []Trip, config Config) {
+	if config.PlanStart == "" || (config.PlanEnd == "" && config.PlanDays <= 0) {
+		fmt.Fprintf(os.Stderr, "Error: plan requires --start and either --end or a positive --days.\n")
+		os.Exit(1)
+	}
+
+	start, err := absence.ParseDate(config.PlanStart)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Invalid date format for --start parameter.\n")
+		os.Exit(1)
+	}
+
+	var end time.Time
+	if config.PlanEnd != "" {
+		end, err = absence.ParseDate(config.PlanEnd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Invalid date format for --end parameter.\n")
+			os.Exit(1)
+		}
+	} else {
+		end = start.AddDate(0, 0, config.PlanDays-1)
+	}
+
+	if end.Before(start) {
+		fmt.Fprintf(os.Stderr, "Error: Planned trip ends before it starts.\n")
+		os.Exit(1)
+	}
+
+	planned := Trip{Start: start, End: end}
+	planned.Days = absence.TripDays(planned, config.DayCount)
+	trips = append(append([]Trip{}, trips...), planned)
+	sortTrips(trips)
+
+	// Evaluate the window ending on the day the planned trip ends
+	config.CustomDate = end.Format("02.01.2006")
+
+	if config.JsonOutput {
+		outputJSON(trips, config)
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("PLANNED TRIP: %s to %s (%d days)\n\n",
+		planned.Start.Format(config.DateFormat), planned.End.Format(config.DateFormat), planned.Days)
+	displayCurrentStatus(trips, config)
+}
+
+// runValidate checks that the trips form a plausible travel history: every
+// row readable, no overlapping trips, input in date order and no trips
+// ending beyond the --horizon. It prints a pass or fail for each check and
+// exits non-zero if any fails.
+func runValidate(trips []Trip, config Config) {
+	problems := 0
+	format := func(trip Trip) string {
+		return trip.Start.Format(config.DateFormat) + " to " + trip.End.Format(config.DateFormat)
+	}
+
+	// check prints a check's verdict, followed by each problem it found
+	check := func(name string, count int, details []string) {
+		if count == 0 {
+			fmt.Printf("%s PASS  %s\n", okMark(config), name)
+			return
+		}
+		fmt.Printf("%s FAIL  %s (%d)\n", issueMark(config), name, count)
+		for _, detail := range details {
+			fmt.Printf("        %s\n", detail)
+		}
+		problems += count
+	}
+
+	fmt.Printf("Checked %d trips from %s\n\n", len(trips), config.Filename)
+
+	// Reversed and unreadable rows were skipped, with their lines on stderr
+	check("Unreadable or reversed rows", config.SkippedRows, nil)
+
+	var details []string
+	overlaps := findOverlaps(trips)
+	for _, o := range overlaps {
+		details = append(details, fmt.Sprintf("Trip %s overlaps trip %s", format(o.First), format(o.Second)))
+	}
+	check("Overlapping trips", len(overlaps), details)
+
+	details = nil
+	for _, trip := range config.OutOfOrder {
+		details = append(details, fmt.Sprintf("Trip %s comes after a later trip", format(trip)))
+	}
+	check("Trips out of date order", len(config.OutOfOrder), details)
+
+	details = nil
+	horizon := absence.TruncateToDay(resolveTargetDate(config)).AddDate(0, 0, config.Horizon)
+	for _, trip := range trips {
+		if trip.End.After(horizon) {
+			details = append(details, fmt.Sprintf("Trip %s ends after %s", format(trip), horizon.Format(config.DateFormat)))
+		}
+	}
+	check(fmt.Sprintf("Trips ending over %d days ahead", config.Horizon), len(details), details)
+
+	if problems > 0 {
+		fmt.Printf("\n%d problem(s) found.\n", problems)
+		os.Exit(1)
+	}
+
+	fmt.Printf("\n%s No problems found.\n", okMark(config))
+}
+
+// runExport writes the analysis in the requested machine-readable format
+func runExport(trips []Trip, config Config) {
+	switch config.ExportFormat {
+	case "json":
+		outputJSON(trips, config)
+	default:
+		fmt.Fprintf(os.Stderr, "Error: Unsupported export format '%s'.\n", config.ExportFormat)
+		os.Exit(1)
+	}
+}
+
+// runNormalize writes the parsed trips back out as sorted dd.mm.yyyy CSV.
+// It takes the trips as read, so --country, --from, --to and --exclude don't
+// drop or clip any of them.
+func runNormalize(trips []Trip, config Config) {
+	sortTrips(trips)
+	if err := writeNormalizedCSV(os.Stdout, trips); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing CSV: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// writeNormalizedCSV writes trips as dd.mm.yyyy CSV, with a Destination
+// column when any trip has one and a Person column when any trip has one.
+// The dates ignore --out-date-format, so that the file always reads back
+// as the same trips.
+func writeNormalizedCSV(w io.Writer, trips []Trip) error {
+	withDestination, withPerson := false, false
+	for _, trip := range trips {
+		withDestination = withDestination || trip.Destination != ""
+		withPerson = withPerson || trip.Person != ""
+	}
+
+	writer := csv.NewWriter(w)
+	header := []string{"Start", "End"}
+	if withDestination {
+		header = append(header, "Destination")
+	}
+	if withPerson {
+		header = append(header, "Person")
+	}
+	writer.Write(header)
+
+	for _, trip := range trips {
+		// --add-trip trips come from the command line, not the file
+		if trip.Projected {
+			continue
+		}
+		row := []string{trip.Start.Format("02.01.2006"), trip.End.Format("02.01.2006")}
+		if trip.Ongoing {
+			row[1] = "ongoing"
+		}
+		if withDestination {
+			row = append(row, trip.Destination)
+		}
+		if withPerson {
+			row = append(row, trip.Person)
+		}
+		writer.Write(row)
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
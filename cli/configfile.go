@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// configFileNames are the default config files looked up in the working
+// directory, in order
+var configFileNames = []string{".stay-within.yaml", ".stay-within.yml", ".stay-within.json"}
+
+// configFileAliases maps config file keys to the flags they set, where the
+// two differ
+var configFileAliases = map[string]string{
+	"date-format": "out-date-format",
+}
+
+// findConfigFile returns the --config path, or the first default config
+// file in the working directory, or "" when there is none
+func findConfigFile(path string) string {
+	if path != "" {
+		return path
+	}
+	for _, name := range configFileNames {
+		if _, err := os.Stat(name); err == nil {
+			return name
+		}
+	}
+	return ""
+}
+
+// readConfigFile reads option defaults from a JSON object, or from YAML
+// "key: value" lines, as flag names and their values
+func readConfigFile(path string) (map[string][]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	options := map[string][]string{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var values map[string]any
+		if err := json.Unmarshal(data, &values); err != nil {
+			return nil, err
+		}
+		for key, value := range values {
+			// A list sets a repeatable flag once per item
+			if items, ok := value.([]any); ok {
+				for _, item := range items {
+					options[key] = append(options[key], fmt.Sprint(item))
+				}
+				continue
+			}
+			options[key] = []string{fmt.Sprint(value)}
+		}
+		return options, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") || text == "---" {
+			continue
+		}
+		key, value, ok := strings.Cut(text, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"key: value\", got %q", line, text)
+		}
+		if i := strings.Index(value, " #"); i >= 0 {
+			value = value[:i]
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		key = strings.TrimSpace(key)
+		options[key] = append(options[key], value)
+	}
+	return options, scanner.Err()
+}
+
+// applyConfigFile sets each option from the config file whose flag was not
+// given on the command line. A --preset on the command line also wins over
+// the file's window and limit.
+func applyConfigFile(fs *flag.FlagSet, path string) {
+	options, err := readConfigFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading config file %s: %v\n", path, err)
+		os.Exit(1)
+	}
+
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	if set["preset"] {
+		set["window"], set["window-days"], set["limit"] = true, true, true
+	}
+
+	// Apply in a fixed order so errors are reported deterministically
+	keys := make([]string, 0, len(options))
+	for key := range options {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		name := key
+		if alias, ok := configFileAliases[key]; ok {
+			name = alias
+		}
+		if fs.Lookup(name) == nil || name == "config" {
+			fmt.Fprintf(os.Stderr, "Error: Unknown option '%s' in config file %s.\n", key, path)
+			os.Exit(1)
+		}
+		if set[name] {
+			continue
+		}
+		for _, value := range options[key] {
+			if err := fs.Set(name, value); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: Invalid %s '%s' in config file %s: %v\n", key, value, path, err)
+				os.Exit(1)
+			}
+		}
+	}
+}
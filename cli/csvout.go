@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+
+	"stay-within/absence"
+)
+
+// outputCSV writes the per-trip analysis as CSV, with dates in
+// --out-date-format, followed by a "Total" row for the window ending on the
+// target date. The Total row has no start date, so reading the file back
+// as trips skips it.
+func outputCSV(trips []Trip, config Config) {
+	targetDate := resolveTargetDate(config)
+	result := absence.Analyze(trips, targetDate, config.Config)
+
+	writer := csv.NewWriter(os.Stdout)
+	writer.Write([]string{"Start", "End", "Days", "Days In Window", "Days Remaining", "Status"})
+
+	for _, row := range result.Trips {
+		writer.Write([]string{
+			row.Trip.Start.Format(config.DateFormat),
+			row.Trip.End.Format(config.DateFormat),
+			strconv.Itoa(row.Trip.Days),
+			strconv.Itoa(row.DaysInWindow),
+			strconv.Itoa(row.DaysRemaining),
+			absence.Status(row.DaysRemaining, config.Config),
+		})
+	}
+
+	writer.Write([]string{
+		"Total",
+		targetDate.Format(config.DateFormat),
+		"",
+		strconv.Itoa(result.TotalDaysOutside),
+		strconv.Itoa(result.DaysRemaining),
+		result.Status,
+	})
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing CSV: %v\n", err)
+		os.Exit(1)
+	}
+}
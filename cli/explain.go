@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"stay-within/absence"
+)
+
+// displayExplanation narrates how the status was worked out: the window,
+// the trips inside it and what each adds, the total and the days remaining
+func displayExplanation(trips []Trip, config Config) {
+	targetDate := absence.TruncateToDay(resolveTargetDate(config))
+	windowStart := absence.WindowStart(targetDate, config.Config)
+	total, contributions := absence.WindowContributions(trips, windowStart, targetDate, config.DayCount)
+	remaining := config.AbsenceLimit - total
+
+	fmt.Println(strings.Repeat("=", config.Width))
+	fmt.Println("HOW THIS WAS CALCULATED")
+	fmt.Println(strings.Repeat("=", config.Width))
+	fmt.Println()
+
+	from := "today"
+	if config.CustomDate != "" {
+		from = "the date you gave (--date)"
+	}
+	fmt.Printf("1. The target date is %s, %s.\n", targetDate.Format(config.DateFormat), from)
+
+	if config.WindowDays > 0 {
+		fmt.Printf("2. The rolling window is the %d days ending on it: %s to %s, both included.\n",
+			config.WindowDays, windowStart.Format(config.DateFormat), targetDate.Format(config.DateFormat))
+	} else {
+		fmt.Printf("2. The rolling window starts %d months before it: %s to %s, both included.\n",
+			config.WindowMonths, windowStart.Format(config.DateFormat), targetDate.Format(config.DateFormat))
+	}
+
+	switch config.DayCount {
+	case absence.Exclusive:
+		fmt.Println("   Departure and return days are not counted as days abroad.")
+	case absence.DepartureOnly:
+		fmt.Println("   Return days are not counted as days abroad.")
+	default:
+		fmt.Println("   Every day of a trip counts, including the departure and return days.")
+	}
+
+	if len(contributions) == 0 {
+		fmt.Println("3. No trips fall inside this window.")
+	} else {
+		fmt.Println("3. These trips fall inside the window:")
+		var days []string
+		for _, c := range contributions {
+			first, last := absence.CountedRange(c.Trip, config.DayCount)
+			first, last = maxTime(first, windowStart), minTime(last, targetDate)
+
+			var notes []string
+			if first.After(c.Trip.Start) || last.Before(c.Trip.End) {
+				notes = append(notes, fmt.Sprintf("only %s to %s is inside the window",
+					first.Format(config.DateFormat), last.Format(config.DateFormat)))
+			}
+			if inside := absence.DaysBetween(first, last) + 1; c.Days < inside {
+				notes = append(notes, fmt.Sprintf("%d already counted for an overlapping trip", inside-c.Days))
+			}
+
+			line := fmt.Sprintf("   - %s to %s: %d %s", c.Trip.Start.Format(config.DateFormat),
+				c.Trip.End.Format(config.DateFormat), c.Days, plural(c.Days, "day", "days"))
+			if len(notes) > 0 {
+				line += " (" + strings.Join(notes, "; ") + ")"
+			}
+			fmt.Println(line)
+			days = append(days, strconv.Itoa(c.Days))
+		}
+		if len(days) == 1 {
+			fmt.Printf("4. In total: %d days outside the UK in the window.\n", total)
+		} else {
+			fmt.Printf("4. Adding them up: %s = %d days outside the UK in the window.\n", strings.Join(days, " + "), total)
+		}
+	}
+
+	fmt.Printf("5. The limit is %d days, so %d - %d = %d days remain.\n", config.AbsenceLimit, config.AbsenceLimit, total, remaining)
+
+	threshold := absence.CautionThreshold(config.Config)
+	switch absence.Status(remaining, config.Config) {
+	case "exceeded":
+		fmt.Printf("6. That is %d days over, so the limit is exceeded.\n", -remaining)
+	case "caution":
+		fmt.Printf("6. %d or fewer days remain, so the status is caution.\n", threshold)
+	default:
+		fmt.Printf("6. More than %d days remain, so the status is ok.\n", threshold)
+	}
+	fmt.Println()
+}
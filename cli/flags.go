@@ -0,0 +1,98 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// countryCodes maps common country names, lower-cased, to ISO 3166-1 alpha-2 codes
+var countryCodes = map[string]string{
+	"australia":            "AU",
+	"austria":              "AT",
+	"belgium":              "BE",
+	"brazil":               "BR",
+	"bulgaria":             "BG",
+	"canada":               "CA",
+	"china":                "CN",
+	"croatia":              "HR",
+	"cyprus":               "CY",
+	"czech republic":       "CZ",
+	"czechia":              "CZ",
+	"denmark":              "DK",
+	"egypt":                "EG",
+	"england":              "GB",
+	"estonia":              "EE",
+	"finland":              "FI",
+	"france":               "FR",
+	"germany":              "DE",
+	"greece":               "GR",
+	"hong kong":            "HK",
+	"hungary":              "HU",
+	"iceland":              "IS",
+	"india":                "IN",
+	"indonesia":            "ID",
+	"ireland":              "IE",
+	"israel":               "IL",
+	"italy":                "IT",
+	"japan":                "JP",
+	"latvia":               "LV",
+	"lithuania":            "LT",
+	"luxembourg":           "LU",
+	"malaysia":             "MY",
+	"malta":                "MT",
+	"mexico":               "MX",
+	"morocco":              "MA",
+	"netherlands":          "NL",
+	"new zealand":          "NZ",
+	"norway":               "NO",
+	"poland":               "PL",
+	"portugal":             "PT",
+	"romania":              "RO",
+	"russia":               "RU",
+	"singapore":            "SG",
+	"slovakia":             "SK",
+	"slovenia":             "SI",
+	"south africa":         "ZA",
+	"south korea":          "KR",
+	"spain":                "ES",
+	"sweden":               "SE",
+	"switzerland":          "CH",
+	"thailand":             "TH",
+	"turkey":               "TR",
+	"ukraine":              "UA",
+	"united arab emirates": "AE",
+	"uae":                  "AE",
+	"united kingdom":       "GB",
+	"uk":                   "GB",
+	"united states":        "US",
+	"usa":                  "US",
+	"vietnam":              "VN",
+}
+
+// flagEmoji returns the flag emoji for a country name or two-letter ISO
+// code, or "" when the destination is not recognised
+func flagEmoji(destination string) string {
+	code, ok := countryCodes[strings.ToLower(strings.TrimSpace(destination))]
+	if !ok {
+		code = strings.ToUpper(strings.TrimSpace(destination))
+		if len(code) != 2 || !unicode.IsLetter(rune(code[0])) || !unicode.IsLetter(rune(code[1])) {
+			return ""
+		}
+	}
+
+	// A flag is the pair of regional indicator symbols for the code's letters
+	var flag strings.Builder
+	for _, letter := range code {
+		flag.WriteRune(0x1F1E6 + letter - 'A')
+	}
+	return flag.String()
+}
+
+// destinationLabel returns the destination prefixed with its flag emoji,
+// or just the destination when no flag is known
+func destinationLabel(destination string) string {
+	if flag := flagEmoji(destination); flag != "" {
+		return flag + " " + destination
+	}
+	return destination
+}
@@ -0,0 +1,459 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"stay-within/absence"
+)
+
+// applicationForecast is the projected standing on a planned application date
+type applicationForecast struct {
+	Date             time.Time
+	TotalDaysOutside int
+	DaysRemaining    int
+	Status           string
+
+	// Highest window total between the target date and the application date
+	PeakDaysOutside int
+	PeakDate        time.Time
+
+	// First window end date that exceeds the limit, zero if none does
+	FirstBreach time.Time
+}
+
+// parseApplyDate parses --apply-date and checks it is not before the target date
+func parseApplyDate(config Config, targetDate time.Time) (time.Time, error) {
+	applyDate, err := absence.ParseDate(config.ApplyDate)
+	if err != nil {
+		return time.Time{}, errors.New("Invalid date format for --apply-date parameter. Use format: dd.mm.yyyy")
+	}
+	if applyDate.Before(absence.TruncateToDay(targetDate)) {
+		return time.Time{}, fmt.Errorf("--apply-date must not be before %s.", targetDate.Format(config.DateFormat))
+	}
+	return applyDate, nil
+}
+
+// resolveApplyDate is parseApplyDate for the text report, exiting on error
+func resolveApplyDate(config Config, targetDate time.Time) time.Time {
+	applyDate, err := parseApplyDate(config, targetDate)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return applyDate
+}
+
+// basisStatus returns the days remaining and status under --status-basis.
+// peakEnd is the end of the worst window when that window decided the
+// result, and zero when the current window did.
+func basisStatus(trips []Trip, targetDate time.Time, config Config) (remainingDays int, status string, peakEnd time.Time) {
+	remainingDays = config.AbsenceLimit - absence.CalculateDaysInWindow(trips, absence.WindowStart(targetDate, config.Config), targetDate, config.DayCount)
+
+	if config.StatusBasis == "worst" || config.StatusBasis == "stricter" {
+		peakTotal, end := absence.PeakWindow(trips, targetDate, config.Config)
+		worstRemaining := config.AbsenceLimit - peakTotal
+
+		if config.StatusBasis == "worst" || worstRemaining < remainingDays {
+			remainingDays, peakEnd = worstRemaining, end
+		}
+	}
+
+	return remainingDays, absence.Status(remainingDays, config.Config), peakEnd
+}
+
+// paceProjection projects the window total on 31 December of the target
+// date's year if travel continues at the pace of the current window: the
+// recorded trips still in that window plus the current rate of days abroad
+// per day for each day left in the year
+func paceProjection(trips []Trip, targetDate time.Time, config Config) (yearEnd time.Time, projected int) {
+	targetDate = absence.TruncateToDay(targetDate)
+	yearEnd = time.Date(targetDate.Year(), 12, 31, 0, 0, 0, 0, time.UTC)
+
+	start := absence.WindowStart(targetDate, config.Config)
+	windowDays := absence.DaysBetween(start, targetDate) + 1
+	rate := float64(absence.CalculateDaysInWindow(trips, start, targetDate, config.DayCount)) / float64(windowDays)
+	daysLeft := absence.DaysBetween(targetDate, yearEnd)
+
+	recorded := absence.CalculateDaysInWindow(trips, absence.WindowStart(yearEnd, config.Config), yearEnd, config.DayCount)
+	return yearEnd, recorded + int(math.Round(rate*float64(daysLeft)))
+}
+
+// displayHeadroom displays the days of allowance left and where the current
+// travel pace leads by the end of the year
+func displayHeadroom(trips []Trip, config Config) {
+	targetDate := resolveTargetDate(config)
+	total := absence.CalculateDaysInWindow(trips, absence.WindowStart(targetDate, config.Config), targetDate, config.DayCount)
+	yearEnd, projected := paceProjection(trips, targetDate, config)
+
+	fmt.Printf("You have %d days of headroom.\n", max(config.AbsenceLimit-total, 0))
+	fmt.Printf("At your current pace (%.1f days/month) you'll use ~%d of %d by %s.\n",
+		averageDaysPerMonth(total, config), projected, config.AbsenceLimit, yearEnd.Format(config.DateFormat))
+	fmt.Println()
+}
+
+// forecastApplication projects the status on applyDate assuming no travel
+// beyond the recorded trips, checking every rolling window that ends
+// between from and applyDate for a breach along the way
+func forecastApplication(trips []Trip, from, applyDate time.Time, config Config) applicationForecast {
+	forecast := applicationForecast{Date: applyDate}
+
+	for day := absence.TruncateToDay(from); !day.After(applyDate); day = day.AddDate(0, 0, 1) {
+		total := absence.CalculateDaysInWindow(trips, absence.WindowStart(day, config.Config), day, config.DayCount)
+
+		if total > forecast.PeakDaysOutside || forecast.PeakDate.IsZero() {
+			forecast.PeakDaysOutside = total
+			forecast.PeakDate = day
+		}
+		if total > config.AbsenceLimit && forecast.FirstBreach.IsZero() {
+			forecast.FirstBreach = day
+		}
+	}
+
+	forecast.TotalDaysOutside = absence.CalculateDaysInWindow(trips, absence.WindowStart(applyDate, config.Config), applyDate, config.DayCount)
+	forecast.DaysRemaining = config.AbsenceLimit - forecast.TotalDaysOutside
+	forecast.Status = absence.Status(forecast.DaysRemaining, config.Config)
+
+	return forecast
+}
+
+// displayApplicationForecast displays the projected status on the application date
+func displayApplicationForecast(trips []Trip, config Config) {
+	targetDate := resolveTargetDate(config)
+	applyDate := resolveApplyDate(config, targetDate)
+	forecast := forecastApplication(trips, targetDate, applyDate, config)
+
+	fmt.Println(strings.Repeat("=", config.Width))
+	fmt.Printf("APPLICATION DATE - %s\n", applyDate.Format(config.DateFormat))
+	fmt.Println(strings.Repeat("=", config.Width))
+	fmt.Println()
+	fmt.Println("Assuming no travel beyond the trips in your file:")
+	fmt.Println()
+
+	fmt.Println(strings.Repeat("-", config.Width))
+	fmt.Printf("Projected days outside UK (last %s): %d days\n", windowLength(config), forecast.TotalDaysOutside)
+	fmt.Printf("Projected days remaining (out of %d):      %d days\n", config.AbsenceLimit, forecast.DaysRemaining)
+	fmt.Printf("Peak window before applying:                %d days (ending %s)\n",
+		forecast.PeakDaysOutside, forecast.PeakDate.Format(config.DateFormat))
+	fmt.Println(strings.Repeat("-", config.Width))
+
+	if !forecast.FirstBreach.IsZero() {
+		fmt.Printf("\n%s WARNING: The rolling window ending %s exceeds the %d-day limit before you apply.\n", warnMark(config),
+			forecast.FirstBreach.Format(config.DateFormat), config.AbsenceLimit)
+	} else if forecast.Status == "caution" {
+		fmt.Printf("\n%s CAUTION: You will be close to the %d-day limit when you apply.\n", warnMark(config), config.AbsenceLimit)
+	} else {
+		fmt.Printf("\n%s No rolling window between %s and %s exceeds the %d-day limit.\n", okMark(config),
+			targetDate.Format(config.DateFormat), applyDate.Format(config.DateFormat), config.AbsenceLimit)
+	}
+
+	fmt.Println()
+}
+
+// monthEndStatus is the rolling-window standing at the end of one month
+type monthEndStatus struct {
+	Date             time.Time
+	TotalDaysOutside int
+	DaysRemaining    int
+	Status           string
+}
+
+// monthEndSeries returns the status at every month-end from the month of the
+// earliest trip through the month of the later of the last trip and the
+// target date, then forecasts one further window length so that every
+// recorded trip has rolled out of the window by the final entry
+func monthEndSeries(trips []Trip, targetDate time.Time, config Config) []monthEndStatus {
+	first := trips[0].Start
+	last := maxTime(trips[len(trips)-1].End, absence.TruncateToDay(targetDate))
+	for _, trip := range trips {
+		first = minTime(first, trip.Start)
+	}
+
+	end := lastDayOfMonth(absence.WindowEnd(last, config.Config))
+
+	var series []monthEndStatus
+	for day := lastDayOfMonth(first); !day.After(end); day = lastDayOfMonth(day.AddDate(0, 0, 1)) {
+		total := absence.CalculateDaysInWindow(trips, absence.WindowStart(day, config.Config), day, config.DayCount)
+		remaining := config.AbsenceLimit - total
+
+		series = append(series, monthEndStatus{
+			Date:             day,
+			TotalDaysOutside: total,
+			DaysRemaining:    remaining,
+			Status:           absence.Status(remaining, config.Config),
+		})
+	}
+
+	return series
+}
+
+// displayMonthly prints the rolling-window total at the end of every month
+// from the earliest trip to the target date, and on the target date itself
+func displayMonthly(trips []Trip, config Config) {
+	targetDate := absence.TruncateToDay(resolveTargetDate(config))
+
+	fmt.Println(strings.Repeat("=", config.Width))
+	fmt.Printf("MONTHLY - Rolling %s total at each month-end\n", windowLabel(config))
+	fmt.Println(strings.Repeat("=", config.Width))
+	fmt.Println()
+	fmt.Printf("%-12s | %-12s | %-14s | %s\n", "Date", "Days Outside", "Days Remaining", "Status")
+	fmt.Println(strings.Repeat("-", config.Width))
+
+	var rows []monthEndStatus
+	for _, month := range monthEndSeries(trips, targetDate, config) {
+		if !month.Date.After(targetDate) {
+			rows = append(rows, month)
+		}
+	}
+	if len(rows) == 0 || !rows[len(rows)-1].Date.Equal(targetDate) {
+		total := absence.CalculateDaysInWindow(trips, absence.WindowStart(targetDate, config.Config), targetDate, config.DayCount)
+		remaining := config.AbsenceLimit - total
+		rows = append(rows, monthEndStatus{
+			Date:             targetDate,
+			TotalDaysOutside: total,
+			DaysRemaining:    remaining,
+			Status:           absence.Status(remaining, config.Config),
+		})
+	}
+
+	for _, row := range rows {
+		fmt.Println(colorize(fmt.Sprintf("%-12s | %12d | %14d | %s",
+			row.Date.Format(config.DateFormat), row.TotalDaysOutside, row.DaysRemaining, row.Status), row.Status, config))
+	}
+	fmt.Println(strings.Repeat("-", config.Width))
+	fmt.Println()
+}
+
+// lastDayOfMonth returns the last day of t's month
+func lastDayOfMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC)
+}
+
+// maxContinuousStay returns the longest trip starting on from that keeps
+// every rolling window within the limit. Old trips age out of the window as
+// the new trip goes on, while recorded future trips can still land in a
+// window that overlaps it, so each length is checked against every window
+// the trip touches. unlimited is true when a trip of a full window length
+// never breaches, i.e. the limit cannot be reached by a single trip.
+func maxContinuousStay(trips []Trip, from time.Time, config Config) (days int, unlimited bool) {
+	start := absence.TruncateToDay(from)
+	windowDays := absence.DaysBetween(absence.WindowStart(start, config.Config), start) + 1
+	safe := plannedTripCheck(trips, start, start, windowDays, config)
+
+	// A longer trip only adds days to each window, so the first unsafe
+	// length ends the search
+	for length := 1; length <= windowDays; length++ {
+		if !safe(start, length) {
+			return length - 1, false
+		}
+	}
+
+	return windowDays, true
+}
+
+// nextRelease returns the date the oldest trip counted in the window ending
+// on targetDate has fully rolled out of the window, and the days that frees.
+// ok is false when no trip counts in the window.
+func nextRelease(trips []Trip, targetDate time.Time, config Config) (date time.Time, days int, ok bool) {
+	targetDate = absence.TruncateToDay(targetDate)
+	windowStart := absence.WindowStart(targetDate, config.Config)
+	_, contributions := absence.WindowContributions(trips, windowStart, targetDate, config.DayCount)
+
+	for _, c := range contributions {
+		if c.Days == 0 {
+			continue
+		}
+		_, last := absence.CountedRange(c.Trip, config.DayCount)
+		last = minTime(last, targetDate)
+
+		// The first window that starts after the trip's last counted day
+		date = targetDate.AddDate(0, 0, 1)
+		for !absence.WindowStart(date, config.Config).After(last) {
+			date = date.AddDate(0, 0, 1)
+		}
+		return date, c.Days, true
+	}
+	return time.Time{}, 0, false
+}
+
+// stayPutMonths are the months ahead of the target date the stay-put
+// projection looks at
+var stayPutMonths = []int{1, 3, 6}
+
+// stayPutPoint is the projected standing some months ahead with no travel
+// beyond the recorded trips
+type stayPutPoint struct {
+	Months           int
+	Date             time.Time
+	TotalDaysOutside int
+	DaysRemaining    int
+}
+
+// stayPutProjection projects the days remaining 1, 3 and 6 months after
+// targetDate if no new trips are taken, as old trips roll out of the window
+func stayPutProjection(trips []Trip, targetDate time.Time, config Config) []stayPutPoint {
+	var points []stayPutPoint
+	for _, months := range stayPutMonths {
+		date := absence.AddMonths(absence.TruncateToDay(targetDate), months)
+		total := absence.CalculateDaysInWindow(trips, absence.WindowStart(date, config.Config), date, config.DayCount)
+		points = append(points, stayPutPoint{months, date, total, config.AbsenceLimit - total})
+	}
+	return points
+}
+
+// forwardWindow is the standing in the window looking forward from the
+// target date (--forward), which counts the trips ahead of it
+type forwardWindow struct {
+	Start            time.Time
+	End              time.Time
+	TotalDaysOutside int
+	DaysRemaining    int
+	Contributions    []absence.Contribution
+}
+
+// forwardStanding counts the days outside in the window that starts on
+// targetDate and runs one window length ahead
+func forwardStanding(trips []Trip, targetDate time.Time, config Config) forwardWindow {
+	start := absence.TruncateToDay(targetDate)
+	end := absence.ForwardWindowEnd(start, config.Config)
+	total, contributions := absence.WindowContributions(trips, start, end, config.DayCount)
+	return forwardWindow{start, end, total, config.AbsenceLimit - total, contributions}
+}
+
+// displayForwardWindow displays the --forward window and the trips in it,
+// labelled so it is not mistaken for the standard window ending on the
+// target date
+func displayForwardWindow(trips []Trip, config Config) {
+	forward := forwardStanding(trips, resolveTargetDate(config), config)
+
+	fmt.Println(strings.Repeat("=", config.Width))
+	fmt.Printf("FORWARD WINDOW - Looking ahead from %s\n", forward.Start.Format(config.DateFormat))
+	fmt.Println(strings.Repeat("=", config.Width))
+	fmt.Printf("Unlike the standard window, which ends on the target date, this one starts on it and runs %s ahead.\n",
+		windowLength(config))
+	fmt.Println()
+	fmt.Printf("Forward window: %s to %s\n", forward.Start.Format(config.DateFormat), forward.End.Format(config.DateFormat))
+	if len(forward.Contributions) == 0 {
+		fmt.Println("No trips fall inside the forward window.")
+	}
+	for _, c := range forward.Contributions {
+		fmt.Printf("  %s to %s: %d %s\n", c.Trip.Start.Format(config.DateFormat), c.Trip.End.Format(config.DateFormat),
+			c.Days, plural(c.Days, "day", "days"))
+	}
+	status := absence.Status(forward.DaysRemaining, config.Config)
+	fmt.Println(colorize(fmt.Sprintf("Days outside ahead: %d days (%d remaining, %s)",
+		forward.TotalDaysOutside, forward.DaysRemaining, status), status, config))
+	fmt.Println()
+}
+
+// maxStayLine is the status line for the longest continuous trip that can
+// start on the target date, days long or unlimited; --max-stay adds the
+// date to be back by, or why there is no limit
+func maxStayLine(days int, unlimited bool, targetDate time.Time, config Config) string {
+	from := "today"
+	if config.CustomDate != "" {
+		from = targetDate.Format(config.DateFormat)
+	}
+	line := fmt.Sprintf("Max continuous trip from %s: ", from)
+
+	switch {
+	case unlimited && config.ShowMaxStay:
+		return line + fmt.Sprintf("no limit (a single trip cannot exceed %d days in %s)", config.AbsenceLimit, windowLength(config))
+	case unlimited:
+		return line + "no limit"
+	case days == 0 && config.ShowMaxStay:
+		return line + "0 days (no allowance left)"
+	case config.ShowMaxStay:
+		return line + fmt.Sprintf("%d days (return by %s)", days,
+			absence.TruncateToDay(targetDate).AddDate(0, 0, days-1).Format(config.DateFormat))
+	}
+	return line + fmt.Sprintf("%d days", days)
+}
+
+// nextSafeTravelDate returns the earliest start on or after from for a trip
+// of length days that keeps every rolling window it touches within the limit.
+// ok is false when no start is ever safe, i.e. the trip alone breaches the
+// limit once every recorded trip has rolled out of the window.
+func nextSafeTravelDate(trips []Trip, from time.Time, length int, config Config) (start time.Time, ok bool) {
+	from = absence.TruncateToDay(from)
+	last := from
+	for _, trip := range trips {
+		last = maxTime(last, trip.End)
+	}
+
+	// From this start no recorded trip is left in any window, so a trip
+	// that is unsafe here is unsafe on every later date too
+	horizon := absence.WindowEnd(last, config.Config).AddDate(0, 0, 1)
+
+	safe := plannedTripCheck(trips, from, horizon, length, config)
+	for start = from; !start.After(horizon); start = start.AddDate(0, 0, 1) {
+		if safe(start, length) {
+			return start, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// plannedTripCheck returns a check of whether a trip starting on start for
+// length days keeps every rolling window it touches within the limit, given
+// the recorded trips. It covers starts from from to latest and lengths up to
+// maxLength.
+func plannedTripCheck(trips []Trip, from, latest time.Time, maxLength int, config Config) func(start time.Time, length int) bool {
+	// Mark the recorded days abroad on a grid running from the earliest
+	// window that can be checked to the last window the trip can reach,
+	// with prefix sums so any window total is a subtraction
+	base := absence.WindowStart(from, config.Config)
+	for _, trip := range trips {
+		base = minTime(base, trip.Start)
+	}
+	gridEnd := absence.WindowEnd(latest.AddDate(0, 0, maxLength), config.Config)
+	for _, trip := range trips {
+		gridEnd = maxTime(gridEnd, trip.End)
+	}
+	index := func(t time.Time) int {
+		return absence.DaysBetween(base, t)
+	}
+
+	abroad := make([]int, index(gridEnd)+2)
+	for _, trip := range trips {
+		first, last := absence.CountedRange(trip, config.DayCount)
+		for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+			abroad[index(day)+1] = 1
+		}
+	}
+	for i := 1; i < len(abroad); i++ {
+		abroad[i] += abroad[i-1]
+	}
+	recorded := func(first, last time.Time) int {
+		if last.Before(first) {
+			return 0
+		}
+		return abroad[index(last)+1] - abroad[index(first)]
+	}
+
+	return func(start time.Time, length int) bool {
+		planned := Trip{Start: start, End: start.AddDate(0, 0, length-1)}
+		first, last := absence.CountedRange(planned, config.DayCount)
+
+		for day := first; !day.After(absence.WindowEnd(last, config.Config)); day = day.AddDate(0, 0, 1) {
+			windowStart := absence.WindowStart(day, config.Config)
+
+			// Planned days in this window that are not already recorded abroad
+			overlapFirst, overlapLast := maxTime(first, windowStart), minTime(last, day)
+			added := 0
+			if !overlapLast.Before(overlapFirst) {
+				added = absence.DaysBetween(overlapFirst, overlapLast) + 1 - recorded(overlapFirst, overlapLast)
+			}
+
+			if recorded(windowStart, day)+added > config.AbsenceLimit {
+				return false
+			}
+		}
+		return true
+	}
+}
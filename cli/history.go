@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"stay-within/absence"
+)
+
+// historySummary aggregates the whole trip history, independent of any
+// rolling window. Excluded trips are left out.
+type historySummary struct {
+	Trips       int
+	TotalDays   int
+	AverageDays float64
+	Longest     Trip
+}
+
+// summarizeHistory totals the trips that count: how many, the days abroad
+// (a day covered by overlapping trips counted once), the average trip
+// length to one decimal and the longest trip
+func summarizeHistory(trips []Trip, config Config) historySummary {
+	var summary historySummary
+	var counted []Trip
+	tripDays := 0
+
+	for _, trip := range trips {
+		if trip.Excluded != "" {
+			continue
+		}
+		counted = append(counted, trip)
+		tripDays += trip.Days
+		if summary.Trips == 0 || trip.Days > summary.Longest.Days {
+			summary.Longest = trip
+		}
+		summary.Trips++
+	}
+	if len(counted) == 0 {
+		return summary
+	}
+
+	last := counted[0].End
+	for _, trip := range counted {
+		last = maxTime(last, trip.End)
+	}
+	summary.TotalDays = absence.CalculateDaysInWindow(counted, firstTripStart(counted), last, config.DayCount)
+	summary.AverageDays = math.Round(float64(tripDays)/float64(summary.Trips)*10) / 10
+	return summary
+}
+
+// displayHistorySummary prints the all-time totals below the status
+func displayHistorySummary(trips []Trip, config Config) {
+	summary := summarizeHistory(trips, config)
+
+	fmt.Println(strings.Repeat("-", config.Width))
+	fmt.Printf("All trips: %d %s, %d days abroad in total\n",
+		summary.Trips, plural(summary.Trips, "trip", "trips"), summary.TotalDays)
+	if summary.Trips > 0 {
+		fmt.Printf("Average trip length: %.1f days\n", summary.AverageDays)
+		fmt.Printf("Longest trip: %d days (%s to %s)\n", summary.Longest.Days,
+			summary.Longest.Start.Format(config.DateFormat), summary.Longest.End.Format(config.DateFormat))
+	}
+	fmt.Println(strings.Repeat("-", config.Width))
+}
+
+// yearTotal is the days abroad in one calendar year
+type yearTotal struct {
+	Year int
+	Days int
+}
+
+// yearTotals sums the days abroad in each calendar year from the first
+// trip's to the last trip's, splitting trips that span 31 December between
+// the two years. Like the all-time total, a day covered by overlapping trips
+// counts once and excluded trips count no days.
+func yearTotals(trips []Trip, config Config) []yearTotal {
+	last := trips[0].End
+	for _, trip := range trips {
+		last = maxTime(last, trip.End)
+	}
+
+	var totals []yearTotal
+	for year := firstTripStart(trips).Year(); year <= last.Year(); year++ {
+		start := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+		end := time.Date(year, 12, 31, 0, 0, 0, 0, time.UTC)
+		totals = append(totals, yearTotal{year, absence.CalculateDaysInWindow(trips, start, end, config.DayCount)})
+	}
+	return totals
+}
+
+// displayYearTotals prints the --group-by-year table
+func displayYearTotals(trips []Trip, config Config) {
+	fmt.Println("Days abroad by calendar year:")
+	fmt.Printf("  %-6s | %s\n", "Year", "Days Abroad")
+	for _, total := range yearTotals(trips, config) {
+		fmt.Printf("  %-6d | %11d\n", total.Year, total.Days)
+	}
+	fmt.Println()
+}
+
+// buildJSONByYear converts the --group-by-year totals to a map keyed by year
+func buildJSONByYear(trips []Trip, config Config) map[string]int {
+	byYear := map[string]int{}
+	for _, total := range yearTotals(trips, config) {
+		byYear[strconv.Itoa(total.Year)] = total.Days
+	}
+	return byYear
+}
+
+// jsonHistory is the all-time summary in JSON output
+type jsonHistory struct {
+	TotalTrips      int            `json:"totalTrips"`
+	TotalDaysAbroad int            `json:"totalDaysAbroad"`
+	AverageTripDays float64        `json:"averageTripDays"`
+	LongestTrip     *jsonDateRange `json:"longestTrip,omitempty"`
+	LongestTripDays int            `json:"longestTripDays"`
+}
+
+// buildJSONHistory converts the all-time summary for JSON output
+func buildJSONHistory(trips []Trip, config Config) jsonHistory {
+	summary := summarizeHistory(trips, config)
+	history := jsonHistory{
+		TotalTrips:      summary.Trips,
+		TotalDaysAbroad: summary.TotalDays,
+		AverageTripDays: summary.AverageDays,
+		LongestTripDays: summary.Longest.Days,
+	}
+	if summary.Trips > 0 {
+		history.LongestTrip = &jsonDateRange{
+			Start: summary.Longest.Start.Format(config.DateFormat),
+			End:   summary.Longest.End.Format(config.DateFormat),
+		}
+	}
+	return history
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"stay-within/absence"
+)
+
+// icalDate is the layout of an all-day iCalendar date
+const icalDate = "20060102"
+
+// icalEscape escapes text for an iCalendar TEXT value
+func icalEscape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(text)
+}
+
+// icalFold splits a content line into 75-octet pieces, each continuation
+// starting with a space, without breaking a UTF-8 sequence
+func icalFold(line string) string {
+	var folded strings.Builder
+	for len(line) > 75 {
+		cut := 75
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		folded.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+	}
+	folded.WriteString(line + "\r\n")
+	return folded.String()
+}
+
+// belowLimitDate returns the first day on or after from whose rolling window
+// is back within the limit, assuming no travel beyond the recorded trips,
+// or zero when from's window is not over the limit
+func belowLimitDate(trips []Trip, from time.Time, config Config) time.Time {
+	day := absence.TruncateToDay(from)
+	if absence.CalculateDaysInWindow(trips, absence.WindowStart(day, config.Config), day, config.DayCount) <= config.AbsenceLimit {
+		return time.Time{}
+	}
+	for absence.CalculateDaysInWindow(trips, absence.WindowStart(day, config.Config), day, config.DayCount) > config.AbsenceLimit {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day
+}
+
+// writeICal writes the trips to path as an iCalendar file of all-day events,
+// each described with its rolling-window standing, plus a reminder on the
+// day the window total drops back within the limit when it is exceeded now
+func writeICal(path string, trips []Trip, config Config) error {
+	var cal strings.Builder
+	stamp := now(config).UTC().Format("20060102T150405Z")
+
+	// writeEvent writes one all-day event; DTEND is the day after the last day
+	writeEvent := func(uid string, start, end time.Time, summary, description string) {
+		cal.WriteString("BEGIN:VEVENT\r\n")
+		fmt.Fprintf(&cal, "UID:%s@stay-within\r\n", uid)
+		fmt.Fprintf(&cal, "DTSTAMP:%s\r\n", stamp)
+		fmt.Fprintf(&cal, "DTSTART;VALUE=DATE:%s\r\n", start.Format(icalDate))
+		fmt.Fprintf(&cal, "DTEND;VALUE=DATE:%s\r\n", end.AddDate(0, 0, 1).Format(icalDate))
+		cal.WriteString(icalFold("SUMMARY:" + icalEscape(summary)))
+		cal.WriteString(icalFold("DESCRIPTION:" + icalEscape(description)))
+		cal.WriteString("TRANSP:TRANSPARENT\r\n")
+		cal.WriteString("END:VEVENT\r\n")
+	}
+
+	cal.WriteString("BEGIN:VCALENDAR\r\n")
+	cal.WriteString("VERSION:2.0\r\n")
+	cal.WriteString("PRODID:-//stay-within//Absence Calculator//EN\r\n")
+	cal.WriteString("CALSCALE:GREGORIAN\r\n")
+
+	targetDate := resolveTargetDate(config)
+	result := absence.Analyze(trips, targetDate, config.Config)
+	for _, row := range result.Trips {
+		summary := fmt.Sprintf("Abroad (%d days)", row.Trip.Days)
+		if row.Trip.Destination != "" {
+			summary = fmt.Sprintf("%s (%d days)", row.Trip.Destination, row.Trip.Days)
+		}
+		description := fmt.Sprintf("%d days outside UK in the %s window ending %s; %d of %d days remaining (%s).",
+			row.DaysInWindow, windowLabel(config), row.Trip.End.Format(config.DateFormat),
+			row.DaysRemaining, config.AbsenceLimit, absence.Status(row.DaysRemaining, config.Config))
+		writeEvent(fmt.Sprintf("trip-%s-%s", row.Trip.Start.Format(icalDate), row.Trip.End.Format(icalDate)),
+			row.Trip.Start, row.Trip.End, summary, description)
+	}
+
+	if day := belowLimitDate(trips, targetDate, config); !day.IsZero() {
+		writeEvent("within-limit-"+day.Format(icalDate), day, day,
+			fmt.Sprintf("Back within the %d-day absence limit", config.AbsenceLimit),
+			fmt.Sprintf("From today the rolling %s window holds no more than %d days outside UK, assuming no further travel.",
+				windowLabel(config), config.AbsenceLimit))
+	}
+
+	cal.WriteString("END:VCALENDAR\r\n")
+
+	return os.WriteFile(path, []byte(cal.String()), 0o644)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"stay-within/absence"
+)
+
+// readTripsInteractive prompts on out for each trip's start and end date,
+// read from in until EOF. Invalid dates and reversed trips are re-prompted
+// rather than skipped.
+func readTripsInteractive(in io.Reader, out io.Writer, config Config) ([]Trip, int, error) {
+	scanner := bufio.NewScanner(in)
+	prompt := func(label string) (string, bool) {
+		fmt.Fprint(out, label)
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return "", false
+		}
+		return strings.TrimSpace(scanner.Text()), true
+	}
+	parse := func(value string) (time.Time, error) {
+		return absence.ParseDate(localizeDate(value, config))
+	}
+
+	fmt.Fprintln(out, "Enter each trip's start and end date, e.g. 01.03.2024. Press Ctrl-D when done.")
+
+	var trips []Trip
+	for {
+		value, ok := prompt(fmt.Sprintf("Trip %d start: ", len(trips)+1))
+		if !ok {
+			break
+		}
+		if value == "" {
+			continue
+		}
+		start, err := parse(value)
+		if err != nil {
+			fmt.Fprintf(out, "Invalid date '%s', please try again.\n", value)
+			continue
+		}
+
+		// A blank end, or EOF, makes a one-day trip
+		end := start
+		for {
+			value, ok := prompt(fmt.Sprintf("Trip %d end (blank for the same day): ", len(trips)+1))
+			if !ok || value == "" {
+				break
+			}
+			last, err := parse(value)
+			if err != nil {
+				fmt.Fprintf(out, "Invalid date '%s', please try again.\n", value)
+				continue
+			}
+			if last.Before(start) {
+				fmt.Fprintf(out, "The trip can't end before it starts (%s), please try again.\n", start.Format(config.DateFormat))
+				continue
+			}
+			end = last
+			break
+		}
+
+		trip := Trip{Start: start, End: end}
+		trip.Days = absence.TripDays(trip, config.DayCount)
+		trips = append(trips, trip)
+	}
+
+	return trips, 0, scanner.Err()
+}
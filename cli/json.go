@@ -0,0 +1,600 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+
+	"stay-within/absence"
+)
+
+// jsonTrip is one row of the per-trip analysis in JSON output
+type jsonTrip struct {
+	Start         string  `json:"start"`
+	End           string  `json:"end"`
+	Days          int     `json:"days"`
+	WindowStart   string  `json:"windowStart"`
+	WindowEnd     string  `json:"windowEnd"`
+	DaysInWindow  int     `json:"daysInWindow"`
+	DaysRemaining int     `json:"daysRemaining"`
+	PercentUsed   float64 `json:"percentUsed"`
+	Destination   string  `json:"destination,omitempty"`
+	Precision     string  `json:"precision,omitempty"`
+	Projected     bool    `json:"projected,omitempty"`
+	Ongoing       bool    `json:"ongoing,omitempty"`
+	Excluded      string  `json:"excluded,omitempty"`
+	Clipped       bool    `json:"clipped,omitempty"`
+}
+
+// jsonStatus is the current/estimated status in JSON output
+type jsonStatus struct {
+	TargetDate  string `json:"targetDate"`
+	LastTripEnd string `json:"lastTripEnd"`
+
+	// DaysSinceLastTrip is 0 when the last trip ends after the target
+	// date, with DaysSinceLastTripNotApplicable set so that a past --date
+	// is not mistaken for a return that day
+	DaysSinceLastTrip              int  `json:"daysSinceLastTrip"`
+	DaysSinceLastTripNotApplicable bool `json:"daysSinceLastTripNotApplicable,omitempty"`
+
+	WindowStart         string   `json:"windowStart"`
+	WindowEnd           string   `json:"windowEnd"`
+	TotalDaysOutside    int      `json:"totalDaysOutside"`
+	DaysRemaining       int      `json:"daysRemaining"`
+	Status              string   `json:"status"`
+	PercentUsed         float64  `json:"percentUsed"`
+	AverageDaysPerMonth *float64 `json:"averageDaysPerMonth,omitempty"`
+	Headroom            *int     `json:"headroom,omitempty"`
+	PaceProjection      *int     `json:"paceProjection,omitempty"`
+	PaceProjectionDate  string   `json:"paceProjectionDate,omitempty"`
+	Basis               string   `json:"basis,omitempty"`
+
+	// AbroadSince is the start of the trip still under way, if any
+	AbroadSince string `json:"abroadSince,omitempty"`
+
+	// AbroadUntil and DaysUntilReturn are set when the target date falls
+	// inside a trip that ends after it; NextTripStart when the next trip
+	// starts after it
+	AbroadUntil     string `json:"abroadUntil,omitempty"`
+	DaysUntilReturn int    `json:"daysUntilReturn,omitempty"`
+	NextTripStart   string `json:"nextTripStart,omitempty"`
+
+	// MaxContinuousTrip is the longest trip starting on the target date that
+	// keeps every window within the limit; a full window length when a
+	// single trip cannot breach it
+	MaxContinuousTrip int `json:"maxContinuousTrip"`
+
+	// NextRelease is when the oldest trip in the window has rolled out of
+	// it, freeing its days
+	NextRelease *jsonRelease `json:"nextRelease,omitempty"`
+}
+
+// jsonRelease is the days freed when the oldest counted trip leaves the window
+type jsonRelease struct {
+	Date string `json:"date"`
+	Days int    `json:"days"`
+}
+
+// jsonPeakWindow is the rolling window with the most days outside
+type jsonPeakWindow struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+	Days  int    `json:"days"`
+}
+
+// jsonApplication is the projected status on the --apply-date
+type jsonApplication struct {
+	Date             string `json:"date"`
+	TotalDaysOutside int    `json:"totalDaysOutside"`
+	DaysRemaining    int    `json:"daysRemaining"`
+	Status           string `json:"status"`
+	PeakDaysOutside  int    `json:"peakDaysOutside"`
+	PeakDate         string `json:"peakDate"`
+	FirstBreach      string `json:"firstBreach,omitempty"`
+}
+
+// jsonMaxStay is the longest continuous trip that can start on the target date
+type jsonMaxStay struct {
+	Days      int    `json:"days"`
+	ReturnBy  string `json:"returnBy,omitempty"`
+	Unlimited bool   `json:"unlimited,omitempty"`
+}
+
+// jsonContribution is the days one trip adds to the current window's total
+type jsonContribution struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+	Days  int    `json:"days"`
+}
+
+// jsonDateRange is a trip's dates
+type jsonDateRange struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// jsonOverlap is a pair of overlapping trips and the days they share
+type jsonOverlap struct {
+	First  jsonDateRange `json:"first"`
+	Second jsonDateRange `json:"second"`
+	Days   int           `json:"days"`
+}
+
+// jsonSchemaVersion is the version of the jsonOutput document. Fields are
+// only ever added within a version; renaming or removing one, or changing
+// its meaning, needs a new version.
+const jsonSchemaVersion = 1
+
+// jsonMeta describes the run that produced a JSON document
+type jsonMeta struct {
+	Version string `json:"version"`
+	Input   string `json:"input"`
+}
+
+// jsonOutput is the top-level JSON document
+type jsonOutput struct {
+	SchemaVersion int      `json:"schemaVersion"`
+	Meta          jsonMeta `json:"meta"`
+
+	Config struct {
+		WindowMonths int    `json:"windowMonths"`
+		WindowDays   int    `json:"windowDays,omitempty"`
+		AbsenceLimit int    `json:"absenceLimit"`
+		DayCount     string `json:"dayCount,omitempty"`
+		Preset       string `json:"preset,omitempty"`
+		Anchor       string `json:"anchor,omitempty"`
+	} `json:"config"`
+	Trips       []jsonTrip       `json:"trips"`
+	Status      jsonStatus       `json:"status"`
+	PeakWindow  jsonPeakWindow   `json:"peakWindow"`
+	Application *jsonApplication `json:"application,omitempty"`
+	MaxStay     *jsonMaxStay     `json:"maxStay,omitempty"`
+
+	// Rules is the result of each --rule
+	Rules []jsonRule `json:"rules,omitempty"`
+
+	// Projection is the standing 1, 3 and 6 months ahead with no new trips
+	Projection []jsonProjection `json:"projection"`
+
+	// DryRun compares each rule without and with the --add-trip trips
+	DryRun *jsonDryRun `json:"dryRun,omitempty"`
+
+	// History summarizes every trip, independent of the rolling window
+	History jsonHistory `json:"history"`
+
+	// ByYear is the days abroad in each calendar year (--group-by-year)
+	ByYear map[string]int `json:"byYear,omitempty"`
+
+	// LongTrips are the trips longer than --max-single days
+	LongTrips []jsonLongTrip `json:"longTrips,omitempty"`
+
+	// ForwardWindow is the window looking ahead from the target date (--forward)
+	ForwardWindow *jsonForwardWindow `json:"forwardWindow,omitempty"`
+
+	// Contributions breaks the current window's total down by trip (--verbose)
+	Contributions []jsonContribution `json:"contributions,omitempty"`
+
+	// NextSafeTravelDate is the earliest safe start for a --plan-days trip,
+	// or "never" when no start is safe
+	NextSafeTravelDate string        `json:"nextSafeTravelDate,omitempty"`
+	Overlaps           []jsonOverlap `json:"overlaps,omitempty"`
+	Warnings           []string      `json:"warnings,omitempty"`
+}
+
+// jsonRule is the standing under one --rule
+type jsonRule struct {
+	Rule             string `json:"rule"`
+	WindowMonths     int    `json:"windowMonths,omitempty"`
+	WindowDays       int    `json:"windowDays,omitempty"`
+	AbsenceLimit     int    `json:"absenceLimit"`
+	WindowStart      string `json:"windowStart"`
+	TotalDaysOutside int    `json:"totalDaysOutside"`
+	DaysRemaining    int    `json:"daysRemaining"`
+	Status           string `json:"status"`
+	PeakDaysOutside  int    `json:"peakDaysOutside"`
+	Pass             bool   `json:"pass"`
+
+	// CautionAt is the days remaining at or below which the rule's status
+	// is caution
+	CautionAt int `json:"cautionAt"`
+}
+
+// jsonProjection is the stay-put standing some months after the target date
+type jsonProjection struct {
+	Months           int    `json:"months"`
+	Date             string `json:"date"`
+	TotalDaysOutside int    `json:"totalDaysOutside"`
+	DaysRemaining    int    `json:"daysRemaining"`
+	Status           string `json:"status"`
+}
+
+// jsonRuleStanding is one side of a dry-run comparison
+type jsonRuleStanding struct {
+	TotalDaysOutside int    `json:"totalDaysOutside"`
+	DaysRemaining    int    `json:"daysRemaining"`
+	PeakDaysOutside  int    `json:"peakDaysOutside"`
+	Status           string `json:"status"`
+}
+
+// jsonDryRunRule is one rule without and with the --add-trip trips
+type jsonDryRunRule struct {
+	Rule   string           `json:"rule"`
+	Before jsonRuleStanding `json:"before"`
+	After  jsonRuleStanding `json:"after"`
+}
+
+// jsonDryRun is the --add-trip comparison for each rule, the main one first
+type jsonDryRun struct {
+	Date  string           `json:"date"`
+	Rules []jsonDryRunRule `json:"rules"`
+}
+
+// jsonLongTrip is a trip longer than --max-single days
+type jsonLongTrip struct {
+	Start  string `json:"start"`
+	End    string `json:"end"`
+	Days   int    `json:"days"`
+	OverBy int    `json:"overBy"`
+}
+
+// jsonForwardWindow is the standing in the window starting on the target date
+type jsonForwardWindow struct {
+	Start            string `json:"start"`
+	End              string `json:"end"`
+	TotalDaysOutside int    `json:"totalDaysOutside"`
+	DaysRemaining    int    `json:"daysRemaining"`
+	Status           string `json:"status"`
+}
+
+// jsonMonthEnd is one entry of the --monthly-json series
+type jsonMonthEnd struct {
+	Date             string `json:"date"`
+	TotalDaysOutside int    `json:"totalDaysOutside"`
+	DaysRemaining    int    `json:"daysRemaining"`
+	Status           string `json:"status"`
+}
+
+// jsonSnapshot is the standing on one --between reference date
+type jsonSnapshot struct {
+	Date             string `json:"date"`
+	WindowStart      string `json:"windowStart"`
+	TotalDaysOutside int    `json:"totalDaysOutside"`
+	DaysRemaining    int    `json:"daysRemaining"`
+	Status           string `json:"status"`
+}
+
+// jsonWindowChange is a trip that entered or left the window
+type jsonWindowChange struct {
+	Start        string `json:"start"`
+	End          string `json:"end"`
+	DaysInWindow int    `json:"daysInWindow"`
+}
+
+// jsonBetween is the --between difference report
+type jsonBetween struct {
+	From   jsonSnapshot `json:"from"`
+	To     jsonSnapshot `json:"to"`
+	Change struct {
+		TotalDaysOutside int `json:"totalDaysOutside"`
+		DaysRemaining    int `json:"daysRemaining"`
+	} `json:"change"`
+	Entered []jsonWindowChange `json:"entered"`
+	Left    []jsonWindowChange `json:"left"`
+}
+
+// outputJSON outputs results as JSON
+func outputJSON(trips []Trip, config Config) {
+	output, err := buildJSONOutput(trips, config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := writeJSON(os.Stdout, output); err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding JSON: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// writeJSON encodes v as indented JSON
+func writeJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// buildJSONOutput computes the per-trip analysis and status for JSON output
+func buildJSONOutput(trips []Trip, config Config) (jsonOutput, error) {
+	output := jsonOutput{
+		SchemaVersion: jsonSchemaVersion,
+		Meta:          jsonMeta{Version: version, Input: config.Filename},
+	}
+	output.Config.WindowMonths = config.WindowMonths
+	output.Config.WindowDays = config.WindowDays
+	output.Config.AbsenceLimit = config.AbsenceLimit
+	output.Config.Preset = config.Preset
+	if config.Anchor == absence.AnchorStart {
+		output.Config.Anchor = string(config.Anchor)
+	}
+	if config.DayCount != absence.Inclusive {
+		output.Config.DayCount = string(config.DayCount)
+	}
+
+	targetDate, err := parseTargetDate(config)
+	if err != nil {
+		return output, err
+	}
+	result := absence.Analyze(trips, targetDate, config.Config)
+
+	// Build trip analysis
+	for _, row := range result.Trips {
+		output.Trips = append(output.Trips, jsonTrip{
+			Start:         row.Trip.Start.Format(config.DateFormat),
+			End:           row.Trip.End.Format(config.DateFormat),
+			Days:          row.Trip.Days,
+			WindowStart:   row.WindowStart.Format(config.DateFormat),
+			WindowEnd:     row.WindowEnd.Format(config.DateFormat),
+			DaysInWindow:  row.DaysInWindow,
+			DaysRemaining: row.DaysRemaining,
+			PercentUsed:   math.Round(percentUsed(row.DaysInWindow, config)*10) / 10,
+			Destination:   row.Trip.Destination,
+			Projected:     row.Trip.Projected,
+			Ongoing:       row.Trip.Ongoing,
+			Excluded:      row.Trip.Excluded,
+			Clipped:       row.Trip.Clipped,
+		})
+		if row.Trip.ByWeek {
+			output.Trips[len(output.Trips)-1].Precision = "week"
+		}
+	}
+
+	// Build status
+	lastTrip := trips[len(trips)-1]
+	totalDaysOutside := result.TotalDaysOutside
+	remainingDays := result.DaysRemaining
+
+	output.Status = jsonStatus{
+		TargetDate:       targetDate.Format(config.DateFormat),
+		LastTripEnd:      lastTrip.End.Format(config.DateFormat),
+		WindowStart:      result.WindowStart.Format(config.DateFormat),
+		WindowEnd:        targetDate.Format(config.DateFormat),
+		TotalDaysOutside: totalDaysOutside,
+		DaysRemaining:    remainingDays,
+		Status:           result.Status,
+		PercentUsed:      math.Round(percentUsed(totalDaysOutside, config)*10) / 10,
+	}
+
+	daysInUK, ok := daysSinceLastTrip(trips, targetDate)
+	output.Status.DaysSinceLastTrip = daysInUK
+	output.Status.DaysSinceLastTripNotApplicable = !ok
+	if ongoing, ok := ongoingTrip(trips); ok {
+		output.Status.AbroadSince = ongoing.Start.Format(config.DateFormat)
+	} else if trip, away, ok := pendingReturn(trips, targetDate); ok && away {
+		output.Status.AbroadUntil = trip.End.Format(config.DateFormat)
+		output.Status.DaysUntilReturn = absence.DaysBetween(targetDate, trip.End)
+	} else if ok {
+		output.Status.NextTripStart = trip.Start.Format(config.DateFormat)
+	}
+
+	maxStay, unlimitedStay := maxContinuousStay(trips, targetDate, config)
+	output.Status.MaxContinuousTrip = maxStay
+	if date, days, ok := nextRelease(trips, targetDate, config); ok {
+		output.Status.NextRelease = &jsonRelease{Date: date.Format(config.DateFormat), Days: days}
+	}
+
+	if config.ShowHeadroom {
+		headroom := max(remainingDays, 0)
+		yearEnd, projected := paceProjection(trips, targetDate, config)
+		output.Status.Headroom = &headroom
+		output.Status.PaceProjection = &projected
+		output.Status.PaceProjectionDate = yearEnd.Format(config.DateFormat)
+	}
+
+	if config.StatusBasis != "" {
+		output.Status.Basis = config.StatusBasis
+		_, output.Status.Status, _ = basisStatus(trips, targetDate, config)
+	}
+
+	for _, r := range evaluateRules(trips, targetDate, config) {
+		output.Rules = append(output.Rules, jsonRule{
+			Rule:             r.Rule.Name,
+			WindowMonths:     r.Rule.WindowMonths,
+			WindowDays:       r.Rule.WindowDays,
+			AbsenceLimit:     r.Rule.AbsenceLimit,
+			WindowStart:      r.WindowStart.Format(config.DateFormat),
+			TotalDaysOutside: r.TotalDaysOutside,
+			DaysRemaining:    r.DaysRemaining,
+			Status:           r.Status,
+			PeakDaysOutside:  r.PeakDaysOutside,
+			Pass:             r.Pass,
+			CautionAt:        absence.CautionThreshold(ruleConfig(config, r.Rule).Config),
+		})
+	}
+
+	for _, point := range stayPutProjection(trips, targetDate, config) {
+		output.Projection = append(output.Projection, jsonProjection{
+			Months:           point.Months,
+			Date:             point.Date.Format(config.DateFormat),
+			TotalDaysOutside: point.TotalDaysOutside,
+			DaysRemaining:    point.DaysRemaining,
+			Status:           absence.Status(point.DaysRemaining, config.Config),
+		})
+	}
+
+	if len(config.ProjectedTrips) > 0 {
+		output.DryRun = buildJSONDryRun(trips, targetDate, config)
+	}
+
+	output.History = buildJSONHistory(trips, config)
+	if config.GroupByYear {
+		output.ByYear = buildJSONByYear(trips, config)
+	}
+	for _, trip := range longTrips(trips, config) {
+		output.LongTrips = append(output.LongTrips, jsonLongTrip{
+			Start:  trip.Start.Format(config.DateFormat),
+			End:    trip.End.Format(config.DateFormat),
+			Days:   trip.Days,
+			OverBy: trip.Days - config.MaxSingle,
+		})
+	}
+	if config.Forward {
+		forward := forwardStanding(trips, targetDate, config)
+		output.ForwardWindow = &jsonForwardWindow{
+			Start:            forward.Start.Format(config.DateFormat),
+			End:              forward.End.Format(config.DateFormat),
+			TotalDaysOutside: forward.TotalDaysOutside,
+			DaysRemaining:    forward.DaysRemaining,
+			Status:           absence.Status(forward.DaysRemaining, config.Config),
+		}
+	}
+
+	output.PeakWindow = jsonPeakWindow{
+		Start: result.PeakWindowStart.Format(config.DateFormat),
+		End:   result.PeakWindowEnd.Format(config.DateFormat),
+		Days:  result.PeakDaysOutside,
+	}
+
+	if config.ShowAverage {
+		average := math.Round(averageDaysPerMonth(totalDaysOutside, config)*10) / 10
+		output.Status.AverageDaysPerMonth = &average
+	}
+
+	if config.ApplyDate != "" {
+		applyDate, err := parseApplyDate(config, targetDate)
+		if err != nil {
+			return output, err
+		}
+		forecast := forecastApplication(trips, targetDate, applyDate, config)
+
+		output.Application = &jsonApplication{
+			Date:             forecast.Date.Format(config.DateFormat),
+			TotalDaysOutside: forecast.TotalDaysOutside,
+			DaysRemaining:    forecast.DaysRemaining,
+			Status:           forecast.Status,
+			PeakDaysOutside:  forecast.PeakDaysOutside,
+			PeakDate:         forecast.PeakDate.Format(config.DateFormat),
+		}
+		if !forecast.FirstBreach.IsZero() {
+			output.Application.FirstBreach = forecast.FirstBreach.Format(config.DateFormat)
+		}
+	}
+
+	for _, o := range findOverlaps(trips) {
+		output.Overlaps = append(output.Overlaps, jsonOverlap{
+			First:  jsonDateRange{Start: o.First.Start.Format(config.DateFormat), End: o.First.End.Format(config.DateFormat)},
+			Second: jsonDateRange{Start: o.Second.Start.Format(config.DateFormat), End: o.Second.End.Format(config.DateFormat)},
+			Days:   o.Days,
+		})
+	}
+
+	if config.DataSummary {
+		output.Warnings = summarizeData(trips, targetDate, config).Warnings()
+	}
+	if first := firstTripStart(trips); absence.TruncateToDay(targetDate).Before(first) {
+		output.Warnings = append(output.Warnings, fmt.Sprintf("target date %s is before the first trip on %s",
+			targetDate.Format(config.DateFormat), first.Format(config.DateFormat)))
+	}
+
+	if config.ShowMaxStay {
+		output.MaxStay = &jsonMaxStay{Days: maxStay, Unlimited: unlimitedStay}
+		if maxStay > 0 && !unlimitedStay {
+			output.MaxStay.ReturnBy = absence.TruncateToDay(targetDate).AddDate(0, 0, maxStay-1).Format(config.DateFormat)
+		}
+	}
+
+	if config.Verbose {
+		_, contributions := absence.WindowContributions(trips, result.WindowStart, targetDate, config.DayCount)
+		output.Contributions = []jsonContribution{}
+		for _, c := range contributions {
+			output.Contributions = append(output.Contributions, jsonContribution{
+				Start: c.Trip.Start.Format(config.DateFormat),
+				End:   c.Trip.End.Format(config.DateFormat),
+				Days:  c.Days,
+			})
+		}
+	}
+
+	if config.SafeTravelDays > 0 {
+		output.NextSafeTravelDate = "never"
+		if start, ok := nextSafeTravelDate(trips, targetDate, config.SafeTravelDays, config); ok {
+			output.NextSafeTravelDate = start.Format(config.DateFormat)
+		}
+	}
+
+	return output, nil
+}
+
+// outputMonthlyJSON outputs the month-end status series as a compact JSON array
+func outputMonthlyJSON(trips []Trip, config Config) {
+	series := monthEndSeries(trips, resolveTargetDate(config), config)
+
+	output := make([]jsonMonthEnd, 0, len(series))
+	for _, month := range series {
+		output = append(output, jsonMonthEnd{
+			Date:             month.Date.Format(config.DateFormat),
+			TotalDaysOutside: month.TotalDaysOutside,
+			DaysRemaining:    month.DaysRemaining,
+			Status:           month.Status,
+		})
+	}
+
+	if err := json.NewEncoder(os.Stdout).Encode(output); err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding JSON: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// outputBetweenJSON outputs the --between difference report as JSON
+func outputBetweenJSON(trips []Trip, config Config) {
+	fromDate, toDate := parseBetween(config)
+	from := takeSnapshot(trips, fromDate, config)
+	to := takeSnapshot(trips, toDate, config)
+	entered, left := compareWindows(trips, from, to, config)
+
+	snapshot := func(s windowSnapshot) jsonSnapshot {
+		return jsonSnapshot{
+			Date:             s.Date.Format(config.DateFormat),
+			WindowStart:      s.WindowStart.Format(config.DateFormat),
+			TotalDaysOutside: s.TotalDaysOutside,
+			DaysRemaining:    s.DaysRemaining,
+			Status:           s.Status,
+		}
+	}
+	changes := func(list []windowChange) []jsonWindowChange {
+		out := []jsonWindowChange{}
+		for _, c := range list {
+			out = append(out, jsonWindowChange{
+				Start:        c.Trip.Start.Format(config.DateFormat),
+				End:          c.Trip.End.Format(config.DateFormat),
+				DaysInWindow: c.Days,
+			})
+		}
+		return out
+	}
+
+	output := jsonBetween{From: snapshot(from), To: snapshot(to), Entered: changes(entered), Left: changes(left)}
+	output.Change.TotalDaysOutside = to.TotalDaysOutside - from.TotalDaysOutside
+	output.Change.DaysRemaining = to.DaysRemaining - from.DaysRemaining
+
+	if err := writeJSON(os.Stdout, output); err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding JSON: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// buildJSONDryRun converts the --add-trip comparison for JSON output
+func buildJSONDryRun(trips []Trip, targetDate time.Time, config Config) *jsonDryRun {
+	date, before, after := dryRun(trips, targetDate, config)
+	standing := func(r ruleResult) jsonRuleStanding {
+		return jsonRuleStanding{r.TotalDaysOutside, r.DaysRemaining, r.PeakDaysOutside, r.Status}
+	}
+
+	output := &jsonDryRun{Date: date.Format(config.DateFormat)}
+	for i := range before {
+		output.Rules = append(output.Rules, jsonDryRunRule{before[i].Rule.Name, standing(before[i]), standing(after[i])})
+	}
+	return output
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// isJSONInput reports whether filename is read as JSON: with --input-format
+// json, or by its .json extension when --input-format is not set
+func isJSONInput(filename string, config Config) bool {
+	if config.InputFormat != "" {
+		return config.InputFormat == "json"
+	}
+	return strings.EqualFold(filepath.Ext(filename), ".json")
+}
+
+// readTripsFromJSON reads trips from a JSON file, or from stdin when
+// filename is "-". The file holds an array of trip objects, or an object
+// with a "trips" or "data" array like a page of the trips API.
+func readTripsFromJSON(filename string, config Config) ([]Trip, int, error) {
+	file := os.Stdin
+	if filename != "-" {
+		var err error
+		file, err = os.Open(filename)
+		if err != nil {
+			return nil, 0, err
+		}
+		defer file.Close()
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var records []map[string]any
+	if err := json.Unmarshal(data, &records); err != nil {
+		var page apiPage
+		if pageErr := json.Unmarshal(data, &page); pageErr != nil {
+			return nil, 0, fmt.Errorf("decoding JSON trips: %w", err)
+		}
+		records = append(page.Trips, page.Data...)
+	}
+
+	return tripsFromRecords(records, config)
+}
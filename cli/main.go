@@ -1,302 +1,1626 @@
 package main
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/csv"
-	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"math"
 	"os"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
+	_ "time/tzdata"
+
+	"stay-within/absence"
 )
 
 // Trip represents a single trip abroad
-type Trip struct {
-	Start time.Time
-	End   time.Time
-	Days  int
-}
+type Trip = absence.Trip
 
 // Config holds command-line configuration
 type Config struct {
-	Filename     string
-	CustomDate   string
-	WindowMonths int
-	AbsenceLimit int
-	JsonOutput   bool
-}
-
-// Supported date formats for parsing
-var dateFormats = []string{
-	"02.01.2006",    // dd.mm.yyyy
-	"02/01/2006",    // dd/mm/yyyy
-	"02-01-2006",    // dd-mm-yyyy
-	"2006-01-02",    // yyyy-mm-dd
-	"2006/01/02",    // yyyy/01/02
-	"2006.01.02",    // yyyy.mm.dd
-	"01/02/2006",    // mm/dd/yyyy (US format)
-	"01-02-2006",    // mm-dd-yyyy
-	"02 Jan 2006",   // dd Mon yyyy
-	"02 January 2006", // dd Month yyyy
+	// Config is the window and limit the trips are checked against
+	absence.Config
+
+	Command    string
+	CustomDate string
+	JsonOutput bool
+	CSVStrict  bool
+
+	// YAMLOutput writes the --json document as YAML instead
+	YAMLOutput bool
+
+	// From and To, when set, limit the trips to the days between them
+	From time.Time
+	To   time.Time
+
+	// ResidenceStart, when set, is the start of the qualifying period:
+	// days abroad before it don't count, like --from
+	ResidenceStart time.Time
+
+	// ProjectedTrips are hypothetical --add-trip trips added to the data
+	ProjectedTrips []Trip
+
+	// ExcludeShorterThan, when positive, lists trips of fewer days without
+	// counting them
+	ExcludeShorterThan int
+
+	// SameDayCounts counts a trip that starts and ends on the same day as
+	// one day; when false such trips are listed without counting them
+	SameDayCounts bool
+
+	// InputFormat is "csv", "json" or "xlsx"; empty picks by file extension
+	InputFormat string
+
+	// Gzip reads the CSV as gzip-compressed, as for a .gz file name
+	Gzip bool
+
+	// Delimiter separates the CSV fields; zero detects it from each file
+	Delimiter rune
+
+	// Comment starts CSV lines that are ignored; zero allows none
+	Comment rune
+
+	// Locale also recognizes that language's month names in dates
+	Locale string
+
+	// TZ names the --tz zone; Location is it loaded, nil for local time
+	TZ       string
+	Location *time.Location
+
+	// Rounding picks the date a timestamp in the input or --date falls on;
+	// trips are counted in whole days from those dates
+	Rounding absence.Rounding
+
+	// Filenames are the CSV files to read and merge, "-" for stdin;
+	// Filename names them all (or the --api-url) in messages
+	Filenames []string
+	Filename  string
+
+	// Preset names the well-known rule the window and limit came from
+	Preset string
+
+	// Rules are further windows and limits to evaluate (--rule)
+	Rules []Rule
+
+	// Strict fails on rows that would otherwise be skipped with a warning
+	Strict bool
+
+	// AutoFixSwapped swaps the start and end of trips that end before they
+	// start, instead of skipping them
+	AutoFixSwapped bool
+
+	// CSVOut writes the per-trip analysis as CSV instead of the text report
+	CSVOut bool
+
+	// CountMode is "abroad" to count every trip, or "countries" to count
+	// only trips whose destination is one of Countries
+	CountMode string
+	Countries []string
+
+	// ExcludeCountries are destinations whose trips do not count
+	ExcludeCountries []string
+
+	// FilteredTrips is the number of trips the country filters left out,
+	// set once the input has been read
+	FilteredTrips int
+
+	// Interactive prompts for the trips on stdin instead of reading a file
+	Interactive bool
+
+	// APIURL is a paginated JSON API to fetch trips from instead of a CSV
+	// file, authenticated with the bearer token APIToken
+	APIURL   string
+	APIToken string
+
+	// StartColumn and EndColumn name the header cells holding the trip
+	// dates, for files where they are not the first two columns, or give
+	// their 1-based column numbers; DestColumn likewise the destination,
+	// and PersonColumn who took the trip, splitting the analysis by person
+	StartColumn  string
+	EndColumn    string
+	DestColumn   string
+	PersonColumn string
+
+	// Planned trip for the plan command
+	PlanStart string
+	PlanEnd   string
+	PlanDays  int
+
+	// ApplyDate is the planned application date to project the status to
+	ApplyDate string
+
+	// Now pins the current time, so tests can fix "today"; zero means the
+	// real time of day
+	Now time.Time
+
+	// Midnight rounds the target date, and so the window bounds, down to
+	// midnight before calculating
+	Midnight bool
+
+	// DataSummary prints a one-line data-quality summary before the status
+	DataSummary bool
+
+	// SkippedRows is the number of input rows skipped while reading, set
+	// once the input has been read
+	SkippedRows int
+
+	// OutOfOrder are the trips that start before the trip read just before
+	// them, set before the trips are sorted
+	OutOfOrder []Trip
+
+	// Horizon is how many days after the target date validate accepts
+	// trips ending
+	Horizon int
+
+	// Width is the width of the text report's tables and separators
+	Width int
+
+	// StatusBasis picks the window the status is judged on: "current",
+	// "worst" (the historical peak) or "stricter" (whichever is worse).
+	// Empty means current, with a zero exit code whatever the status.
+	StatusBasis string
+
+	// ActiveOnly lists only the trips overlapping the window ending on the
+	// target date in the per-trip table
+	ActiveOnly bool
+
+	// Sort orders the rows of the per-trip table: "end" (the default),
+	// "start", "days" (longest first) or "remaining" (fewest first)
+	Sort string
+
+	// ShowFlags prefixes each destination in the per-trip table with its
+	// flag emoji
+	ShowFlags bool
+
+	// ShowHeadroom reports the days left as headroom and projects the
+	// current travel pace to the end of the year
+	ShowHeadroom bool
+
+	// ShowAverage adds the average days abroad per month over the window
+	ShowAverage bool
+
+	// MonthlyJSON outputs the status at every month-end as a JSON array
+	MonthlyJSON bool
+
+	// Serve is the address to serve the JSON status on, e.g. ":8080"
+	Serve string
+
+	// Between holds two comma-separated dates to compare the status at
+	Between string
+
+	// Monthly adds the rolling total at every month-end to the text report
+	Monthly bool
+
+	// Chart draws the per-trip window totals as a bar chart (terminal only)
+	Chart bool
+
+	// ShowMaxStay reports the longest continuous trip that can start on the target date
+	ShowMaxStay bool
+
+	// GroupByYear adds the days abroad in each calendar year
+	GroupByYear bool
+
+	// Forward adds the window looking forward from the target date
+	Forward bool
+
+	// Calendar, when set, prints a month calendar of the days abroad
+	// instead of the analysis: for a year such as "2024", or "all" for
+	// every month with a trip
+	Calendar string
+
+	// FirstDayOfWeek is the weekday the --calendar weeks start on
+	FirstDayOfWeek time.Weekday
+
+	// TaxYear reports the days outside in each fixed UK tax year (6 April
+	// to 5 April) instead of rolling windows
+	TaxYear bool
+
+	// Quiet prints only the totals and status line instead of the full report
+	Quiet bool
+
+	// Explain narrates how the current status was calculated
+	Explain bool
+
+	// Verbose lists the trips that make up the current window's total
+	Verbose bool
+
+	// DateFormat is the Go layout dates are printed with
+	DateFormat string
+
+	// NoColor turns off colors and emoji flags, as does the NO_COLOR
+	// environment variable
+	NoColor bool
+
+	// ASCII replaces emoji and other non-ASCII markers with plain text
+	ASCII bool
+
+	// Color shows statuses in color; set when stdout is a terminal and
+	// NoColor is not
+	Color bool
+
+	// SafeTravelDays is the length of a trip to find the next safe start date for
+	SafeTravelDays int
+
+	// MaxSingle, when positive, flags each trip longer than this many days,
+	// a cap on any single absence separate from the rolling window
+	MaxSingle int
+
+	// XLSXOut is the path to write the per-trip analysis spreadsheet to
+	XLSXOut string
+
+	// ICalOut is the path to write the trips to as an iCalendar file
+	ICalOut string
+
+	// Bundle is a directory to write the input, config and output to
+	Bundle string
+
+	// FlagValues is every flag's effective value by name, for --bundle
+	FlagValues map[string]any
+
+	// ExportFormat selects the export command's output format
+	ExportFormat string
 }
 
-func main() {
-	config := parseArgs()
+// version is the tool's version, set at build time with
+// -ldflags "-X main.version=..."
+var version = "dev"
+
+func main() {
+	config := parseArgs(os.Args[1:])
+
+	// Check if file exists
+	if config.APIURL == "" {
+		for _, filename := range config.Filenames {
+			if _, err := os.Stat(filename); filename != "-" && os.IsNotExist(err) {
+				fmt.Fprintf(os.Stderr, "Error: File '%s' not found.\n", filename)
+				os.Exit(1)
+			}
+		}
+	}
+
+	// Read and parse CSV, or fetch from the API
+	trips, skipped, err := readTrips(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading trips: %v\n", err)
+		os.Exit(1)
+	}
+
+	// --bundle and normalize save the trips as read, before any filtering
+	input := slices.Clone(trips)
+	trips, config = prepareTrips(trips, skipped, config)
+	if skipped > 0 {
+		fmt.Fprintf(os.Stderr, "Skipped %d invalid %s.\n", skipped, plural(skipped, "row", "rows"))
+	}
+
+	// A file with no valid rows at all still fails below
+	if config.Command == "normalize" && len(input) > 0 {
+		runNormalize(input, config)
+		return
+	}
+
+	if len(trips) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: No valid trip data found in '%s'.\n", config.Filename)
+		fmt.Fprintf(os.Stderr, "Expected format: Start date, End date (with or without header)\n")
+		fmt.Fprintf(os.Stderr, "Supported date formats: dd.mm.yyyy, dd/mm/yyyy, yyyy-mm-dd, mm/dd/yyyy, etc.\n\n")
+		os.Exit(1)
+	}
+
+	if config.PersonColumn != "" {
+		runPerPerson(trips, config)
+		return
+	}
+
+	// JSON output reports overlaps in an "overlaps" array instead, and
+	// validate lists them itself
+	if !config.JsonOutput && !config.YAMLOutput && !config.MonthlyJSON && !config.CSVOut && config.Command != "export" && config.Command != "validate" {
+		warnOverlaps(trips, config)
+	}
+
+	switch config.Command {
+	case "plan":
+		runPlan(trips, config)
+	case "validate":
+		runValidate(trips, config)
+	case "export":
+		runExport(trips, config)
+	default:
+		if config.Bundle != "" {
+			runBundle(input, trips, config)
+		} else {
+			runAnalyze(trips, config)
+		}
+
+		// An explicit --status-basis makes the exit code reflect the status
+		if config.StatusBasis != "" {
+			if _, status, _ := basisStatus(trips, resolveTargetDate(config), config); status == "exceeded" {
+				os.Exit(2)
+			}
+		}
+	}
+}
+
+// runAnalyze displays the per-trip analysis and current status
+func runAnalyze(trips []Trip, config Config) {
+	if config.Serve != "" {
+		runServe(config)
+		return
+	}
+
+	if config.Between != "" {
+		if config.JsonOutput {
+			outputBetweenJSON(trips, config)
+		} else {
+			displayBetween(trips, config)
+		}
+	} else if config.Calendar != "" {
+		displayCalendar(trips, config)
+	} else if config.TaxYear {
+		if config.JsonOutput {
+			outputTaxYearsJSON(trips, config)
+		} else {
+			displayTaxYears(trips, config)
+		}
+	} else if config.MonthlyJSON {
+		outputMonthlyJSON(trips, config)
+	} else if config.JsonOutput {
+		outputJSON(trips, config)
+	} else if config.YAMLOutput {
+		outputYAML(trips, config)
+	} else if config.CSVOut {
+		outputCSV(trips, config)
+	} else if config.Quiet {
+		displayQuietStatus(trips, config)
+	} else {
+		// Display per-trip analysis
+		displayTripAnalysis(trips, config)
+
+		if config.Monthly {
+			displayMonthly(trips, config)
+		}
+
+		if config.DataSummary {
+			fmt.Println(summarizeData(trips, resolveTargetDate(config), config))
+			fmt.Println()
+		}
+
+		// Display current/estimated status
+		displayCurrentStatus(trips, config)
+
+		if config.Explain {
+			displayExplanation(trips, config)
+		}
+
+		if len(config.Rules) > 0 {
+			displayRules(trips, config)
+		}
+
+		if len(config.ProjectedTrips) > 0 {
+			displayDryRun(trips, config)
+		}
+
+		if config.GroupByYear {
+			displayYearTotals(trips, config)
+		}
+
+		if config.Forward {
+			displayForwardWindow(trips, config)
+		}
+
+		if config.ShowHeadroom {
+			displayHeadroom(trips, config)
+		}
+
+		if config.ApplyDate != "" {
+			displayApplicationForecast(trips, config)
+		}
+	}
+
+	if config.XLSXOut != "" {
+		if err := writeXLSX(config.XLSXOut, trips, config); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing spreadsheet: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Wrote spreadsheet to %s\n", config.XLSXOut)
+	}
+
+	if config.ICalOut != "" {
+		if err := writeICal(config.ICalOut, trips, config); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing calendar: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Wrote calendar to %s\n", config.ICalOut)
+	}
+}
+
+// prepareTrips applies --countries, the date range and the exclusions to
+// the trips read, then sorts them, recording the filtered, skipped and
+// out-of-order rows in config. Both main and --serve use it, so they
+// analyze the same trips for the same flags.
+func prepareTrips(trips []Trip, skipped int, config Config) ([]Trip, Config) {
+	read := len(trips)
+	trips = filterByCountries(trips, config)
+	config.FilteredTrips = read - len(trips)
+	trips = filterByDateRange(trips, config)
+	trips = excludeTrips(trips, config)
+	config.SkippedRows = skipped
+	config.OutOfOrder = findOutOfOrder(trips)
+	sortTrips(trips)
+	return trips, config
+}
+
+// truncateText shortens text to at most width characters, ending it with
+// "..." when it is cut
+func truncateText(text string, width int) string {
+	runes := []rune(text)
+	if len(runes) <= width {
+		return text
+	}
+	if width <= 3 {
+		return string(runes[:max(width, 0)])
+	}
+	return string(runes[:width-3]) + "..."
+}
+
+// sortTrips sorts trips by end date, then by start date as a tiebreaker so
+// that the order is deterministic when two trips share the same end date.
+func sortTrips(trips []Trip) {
+	sort.Slice(trips, func(i, j int) bool {
+		if trips[i].End.Equal(trips[j].End) {
+			return trips[i].Start.Before(trips[j].Start)
+		}
+		return trips[i].End.Before(trips[j].End)
+	})
+}
+
+// activeRows returns the per-trip rows whose trips overlap the window from
+// windowStart to windowEnd
+func activeRows(rows []absence.TripResult, windowStart, windowEnd time.Time) []absence.TripResult {
+	var active []absence.TripResult
+	for _, row := range rows {
+		if !row.Trip.End.Before(windowStart) && !row.Trip.Start.After(windowEnd) {
+			active = append(active, row)
+		}
+	}
+	return active
+}
+
+// sortRows returns the per-trip rows in the --sort display order. The rows
+// come in end date order, which ties keep.
+func sortRows(rows []absence.TripResult, by string) []absence.TripResult {
+	rows = slices.Clone(rows)
+	switch by {
+	case "start":
+		sort.SliceStable(rows, func(i, j int) bool { return rows[i].Trip.Start.Before(rows[j].Trip.Start) })
+	case "days":
+		sort.SliceStable(rows, func(i, j int) bool { return rows[i].Trip.Days > rows[j].Trip.Days })
+	case "remaining":
+		sort.SliceStable(rows, func(i, j int) bool { return rows[i].DaysRemaining < rows[j].DaysRemaining })
+	}
+	return rows
+}
+
+// parseArgs parses command-line arguments, including the optional subcommand
+func parseArgs(args []string) Config {
+	config := Config{
+		Config:         absence.Config{WindowMonths: 12, AbsenceLimit: 180},
+		Command:        "analyze",
+		FirstDayOfWeek: time.Monday,
+	}
+
+	// A bare "stay-within trips.csv" is a shortcut for "stay-within analyze trips.csv"
+	if len(args) > 0 && findCommand(args[0]) != nil {
+		config.Command = args[0]
+		args = args[1:]
+	}
+	cmd := findCommand(config.Command)
+
+	// Create a new FlagSet per subcommand to allow flags after positional arguments
+	fs := flag.NewFlagSet(os.Args[0]+" "+cmd.Name, flag.ExitOnError)
+	configPath := fs.String("config", "", "Read option defaults from this file (default: .stay-within.yaml or .stay-within.json)")
+	fs.StringVar(&config.CustomDate, "date", "", "Use a specific date for calculation instead of today (format: dd.mm.yyyy)")
+	from := fs.String("from", "", "Ignore trip days before this date")
+	residenceStart := fs.String("residence-start", "", "Start of the qualifying period; trip days before it don't count")
+	to := fs.String("to", "", "Ignore trip days after this date")
+	var addTrips []string
+	fs.Func("add-trip", "Add a hypothetical trip, start:end (repeatable)", func(value string) error {
+		addTrips = append(addTrips, value)
+		return nil
+	})
+	fs.IntVar(&config.ExcludeShorterThan, "exclude-shorter-than", 0, "List trips shorter than this many days without counting them")
+	fs.BoolVar(&config.SameDayCounts, "same-day-counts", true, "Count a trip that starts and ends on the same day as one day")
+	fs.IntVar(&config.WindowMonths, "window", 12, "Rolling window period in months")
+	fs.IntVar(&config.WindowDays, "window-days", 0, "Rolling window period in days, overriding --window (e.g. 180 for Schengen)")
+	fs.IntVar(&config.AbsenceLimit, "limit", 180, "Maximum allowed absence days in window")
+	fs.IntVar(&config.WarnAt, "warn-at", 0, "Show caution at or below this many days remaining (default: under 15% of the limit, at most 30)")
+	var rules []string
+	fs.Func("rule", "Also evaluate a window:limit rule such as 60mo:450, or a preset (repeatable)", func(value string) error {
+		rule, err := parseRule(value)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, value)
+		config.Rules = append(config.Rules, rule)
+		return nil
+	})
+	fs.StringVar(&config.Preset, "preset", "", "Use a well-known rule's window and limit (e.g. ilr-5yr, schengen)")
+	fs.BoolVar(&config.CSVStrict, "csv-strict", false, "Enforce RFC 4180 CSV parsing (consistent field counts, proper quoting)")
+	fs.BoolVar(&config.Strict, "strict", false, "Fail on invalid rows (unparseable dates, end before start) instead of skipping them")
+	fs.BoolVar(&config.AutoFixSwapped, "auto-fix-swapped", false, "Swap the start and end of trips that end before they start, instead of skipping them")
+	fs.BoolVar(&config.Gzip, "gzip", false, "Read gzip-compressed CSV (default: for .gz files)")
+	delimiter := fs.String("delimiter", "", "Field delimiter: ',', '\\t', ';' or '|' (default: detected)")
+	commentChar := fs.String("comment-char", "#", "Ignore CSV lines starting with this character (\"\" for none)")
+	fs.StringVar(&config.Locale, "locale", "en", "Language of month names in dates: en, de, fr or es")
+	fs.StringVar(&config.TZ, "tz", "", "IANA time zone for today and timestamps, e.g. Europe/London (default: local)")
+	fs.StringVar(&config.InputFormat, "input-format", "", "Input format: csv, json or xlsx (default: by file extension)")
+	rounding := fs.String("rounding", "floor", "Date a timestamp falls on: floor (as written), ceil or round")
+	dayCount := fs.String("day-count", "inclusive", "Days of each trip that count: inclusive, exclusive or departure-only")
+	returnDayCounts := fs.Bool("return-day-counts", true, "Count the day of return as a day abroad (false: one day fewer per trip)")
+	fs.StringVar(&config.CountMode, "count-mode", "abroad", "Count days abroad (abroad) or only in --countries (countries)")
+	countries := fs.String("countries", "", "Comma-separated destinations that count when --count-mode is countries")
+	onlyCountries := fs.String("only-countries", "", "Count only trips to these comma-separated destinations")
+	excludeCountries := fs.String("exclude-countries", "", "Don't count trips to these comma-separated destinations")
+	fs.StringVar(&config.StartColumn, "start-column", "", "Header name of the trip start date column")
+	fs.StringVar(&config.EndColumn, "end-column", "", "Header name of the trip end date column")
+	fs.StringVar(&config.PersonColumn, "person-col", "", "Header name or number of a column naming who took each trip; analyzes each person separately")
+	columns := fs.String("columns", "", "Column mapping, e.g. start=departure,end=return,destination=country")
+	fs.BoolVar(&config.Midnight, "midnight", true, "Round the target date and window bounds to midnight")
+	fs.IntVar(&config.Width, "width", 0, "Width of the text report (default: terminal width, up to 90)")
+	fs.BoolVar(&config.NoColor, "no-color", false, "Print plain text without colors or flag emoji")
+	fs.BoolVar(&config.ASCII, "ascii", false, "Print [WARN]/[OK] markers instead of emoji (default when the locale is not UTF-8)")
+	fs.StringVar(&config.DateFormat, "out-date-format", "02.01.2006", "Date format for output: iso, uk, us or a Go layout")
+	fs.StringVar(&config.APIURL, "api-url", "", "Fetch trips from a paginated JSON API instead of a CSV file")
+	fs.BoolVar(&config.Interactive, "interactive", false, "Enter the trips at prompts instead of reading a CSV file")
+	fs.StringVar(&config.APIToken, "api-token", "", "Bearer token for --api-url")
+	cmd.Flags(fs, &config)
+
+	fs.Usage = func() {
+		printUsage(cmd)
+	}
+
+	// Manually separate filenames and flags
+	var filenames []string
+	var flagArgs []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if strings.HasPrefix(arg, "-") && arg != "-" {
+			flagArgs = append(flagArgs, arg)
+			if strings.Contains(arg, "=") || isBoolFlag(fs, arg) {
+				// Boolean flags and --name=value forms never take the next arg
+				continue
+			}
+
+			// Take as many following args as the flag has values (usually
+			// one), joining multiple values with commas
+			var values []string
+			for len(values) < flagValueCount(arg) && i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				i++
+				values = append(values, args[i])
+			}
+			if len(values) > 0 {
+				flagArgs = append(flagArgs, strings.Join(values, ","))
+			}
+		} else {
+			filenames = append(filenames, arg)
+		}
+	}
+
+	// Parse flags, then fill in the ones not given from the config file
+	fs.Parse(flagArgs)
+	if path := findConfigFile(*configPath); path != "" {
+		applyConfigFile(fs, path)
+	}
+	if config.Preset != "" {
+		applyPreset(fs, &config)
+	}
+	config.FlagValues = flagValues(fs, map[string][]string{"add-trip": addTrips, "rule": rules})
+
+	// Check for filenames; with --api-url the URL stands in for them
+	if config.APIURL != "" {
+		if len(filenames) > 0 {
+			fmt.Fprintf(os.Stderr, "Error: Give either a CSV file or --api-url, not both.\n")
+			os.Exit(1)
+		}
+		filenames = []string{config.APIURL}
+	}
+	if config.Interactive {
+		if len(filenames) > 0 {
+			fmt.Fprintf(os.Stderr, "Error: Give only one of a CSV file, --api-url or --interactive.\n")
+			os.Exit(1)
+		}
+		if config.Serve != "" {
+			fmt.Fprintf(os.Stderr, "Error: --serve re-reads the trips on every request and cannot prompt for them.\n")
+			os.Exit(1)
+		}
+		config.Filename = "interactive input"
+	} else if len(filenames) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: CSV file argument is required.\n\n")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	config.Filenames = filenames
+	if !config.Interactive {
+		config.Filename = strings.Join(filenames, ", ")
+	}
+	stdinCount := 0
+	for _, filename := range filenames {
+		if filename == "-" {
+			stdinCount++
+		}
+	}
+	if stdinCount > 0 && config.Serve != "" {
+		fmt.Fprintf(os.Stderr, "Error: --serve re-reads the CSV on every request and cannot read it from stdin.\n")
+		os.Exit(1)
+	}
+	if stdinCount > 1 {
+		fmt.Fprintf(os.Stderr, "Error: stdin (-) can only be read once.\n")
+		os.Exit(1)
+	}
+	config.Countries = splitList(*countries)
+	config.ExcludeCountries = splitList(*excludeCountries)
+
+	// --only-countries is short for --count-mode countries --countries
+	if only := splitList(*onlyCountries); len(only) > 0 {
+		config.CountMode = "countries"
+		config.Countries = append(config.Countries, only...)
+	}
+
+	// Validate count mode
+	switch config.CountMode {
+	case "abroad":
+	case "countries":
+		if len(config.Countries) == 0 {
+			fmt.Fprintf(os.Stderr, "Error: --count-mode countries requires a --countries list.\n")
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Error: --count-mode must be 'abroad' or 'countries'.\n")
+		os.Exit(1)
+	}
+
+	// Parse the trip date range
+	for _, bound := range []struct {
+		name  string
+		value string
+		date  *time.Time
+	}{{"from", *from, &config.From}, {"to", *to, &config.To}, {"residence-start", *residenceStart, &config.ResidenceStart}} {
+		if bound.value == "" {
+			continue
+		}
+		date, err := absence.ParseDate(bound.value)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Invalid date format for --%s parameter. Use format: dd.mm.yyyy\n", bound.name)
+			os.Exit(1)
+		}
+		*bound.date = date
+	}
+	if !config.From.IsZero() && !config.To.IsZero() && config.To.Before(config.From) {
+		fmt.Fprintf(os.Stderr, "Error: --to must not be before --from.\n")
+		os.Exit(1)
+	}
+	if !config.ResidenceStart.IsZero() && !config.To.IsZero() && config.To.Before(config.ResidenceStart) {
+		fmt.Fprintf(os.Stderr, "Error: --to must not be before --residence-start.\n")
+		os.Exit(1)
+	}
+
+	switch config.InputFormat {
+	case "", "csv", "json", "xlsx":
+	default:
+		fmt.Fprintf(os.Stderr, "Error: --input-format must be 'csv', 'json' or 'xlsx'.\n")
+		os.Exit(1)
+	}
+
+	if !slices.Contains(absence.Locales, config.Locale) {
+		fmt.Fprintf(os.Stderr, "Error: --locale must be one of %s.\n", strings.Join(absence.Locales, ", "))
+		os.Exit(1)
+	}
+
+	if *columns != "" {
+		for _, pair := range splitList(*columns) {
+			key, value, _ := strings.Cut(pair, "=")
+			value = strings.TrimSpace(value)
+			switch strings.ToLower(strings.TrimSpace(key)) {
+			case "start":
+				config.StartColumn = value
+			case "end":
+				config.EndColumn = value
+			case "destination", "country":
+				config.DestColumn = value
+			case "person", "name":
+				config.PersonColumn = value
+			default:
+				fmt.Fprintf(os.Stderr, "Error: --columns entries must be start=, end=, destination= or person=, got '%s'.\n", pair)
+				os.Exit(1)
+			}
+			if value == "" {
+				fmt.Fprintf(os.Stderr, "Error: --columns entry '%s' has no column.\n", pair)
+				os.Exit(1)
+			}
+		}
+	}
+
+	if config.TZ != "" {
+		location, err := time.LoadLocation(config.TZ)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Unknown --tz '%s'. Use an IANA name such as Europe/London.\n", config.TZ)
+			os.Exit(1)
+		}
+		config.Location = location
+	}
+
+	// Parse the hypothetical --add-trip trips like CSV rows
+	for _, value := range addTrips {
+		startValue, endValue, _ := strings.Cut(value, ":")
+		start, err := absence.ParseDate(startValue)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Invalid --add-trip '%s'. Use start:end, e.g. 01.06.2026:15.06.2026\n", value)
+			os.Exit(1)
+		}
+		end := start
+		if endValue != "" {
+			if end, err = absence.ParseDate(endValue); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: Invalid --add-trip '%s'. Use start:end, e.g. 01.06.2026:15.06.2026\n", value)
+				os.Exit(1)
+			}
+		}
+		if end.Before(start) {
+			fmt.Fprintf(os.Stderr, "Error: --add-trip '%s' ends before it starts.\n", value)
+			os.Exit(1)
+		}
+		config.ProjectedTrips = append(config.ProjectedTrips, Trip{Start: start, End: end, Projected: true})
+	}
+
+	if config.ExcludeShorterThan < 0 {
+		fmt.Fprintf(os.Stderr, "Error: --exclude-shorter-than must not be negative.\n")
+		os.Exit(1)
+	}
+
+	// Validate the delimiter; unset means detect it from the file
+	switch *delimiter {
+	case "":
+	case ",", ";", "|":
+		config.Delimiter = rune((*delimiter)[0])
+	case "\t", `\t`, "tab":
+		config.Delimiter = '\t'
+	default:
+		fmt.Fprintf(os.Stderr, "Error: --delimiter must be ',', '\\t', ';' or '|'.\n")
+		os.Exit(1)
+	}
+
+	// Validate the comment character; empty means lines are never comments
+	if *commentChar != "" {
+		comment := []rune(*commentChar)
+		if len(comment) != 1 || slices.Contains(csvDelimiters, comment[0]) || strings.ContainsRune("\" \r\n", comment[0]) {
+			fmt.Fprintf(os.Stderr, "Error: --comment-char must be a single character other than a delimiter, quote or space.\n")
+			os.Exit(1)
+		}
+		config.Comment = comment[0]
+	}
+
+	switch config.Anchor {
+	case "", absence.AnchorEnd, absence.AnchorStart:
+	default:
+		fmt.Fprintf(os.Stderr, "Error: --anchor must be 'end' or 'start'.\n")
+		os.Exit(1)
+	}
+
+	config.Rounding = absence.Rounding(*rounding)
+	switch config.Rounding {
+	case absence.Floor, absence.Ceil, absence.Round:
+	default:
+		fmt.Fprintf(os.Stderr, "Error: --rounding must be 'floor', 'ceil' or 'round'.\n")
+		os.Exit(1)
+	}
+
+	// Validate day counting
+	config.DayCount = absence.DayCount(*dayCount)
+	switch config.DayCount {
+	case absence.Inclusive, absence.Exclusive, absence.DepartureOnly:
+	default:
+		fmt.Fprintf(os.Stderr, "Error: --day-count must be 'inclusive', 'exclusive' or 'departure-only'.\n")
+		os.Exit(1)
+	}
+
+	// --return-day-counts=false is departure-only counting; exclusive
+	// already leaves the return day out
+	if !*returnDayCounts && config.DayCount == absence.Inclusive {
+		config.DayCount = absence.DepartureOnly
+	}
+
+	// Validate window and limit
+	if config.WindowMonths <= 0 {
+		fmt.Fprintf(os.Stderr, "Error: --window must be a positive number of months.\n")
+		os.Exit(1)
+	}
+	if config.WindowDays < 0 {
+		fmt.Fprintf(os.Stderr, "Error: --window-days must be a positive number of days.\n")
+		os.Exit(1)
+	}
+	if config.AbsenceLimit <= 0 {
+		fmt.Fprintf(os.Stderr, "Error: --limit must be a positive number of days.\n")
+		os.Exit(1)
+	}
+	if config.WarnAt < 0 {
+		fmt.Fprintf(os.Stderr, "Error: --warn-at must not be negative.\n")
+		os.Exit(1)
+	}
+	if config.SafeTravelDays < 0 {
+		fmt.Fprintf(os.Stderr, "Error: --plan-days must be a positive number of days.\n")
+		os.Exit(1)
+	}
+	if config.MaxSingle < 0 {
+		fmt.Fprintf(os.Stderr, "Error: --max-single must be a positive number of days.\n")
+		os.Exit(1)
+	}
+	if config.Width < 0 {
+		fmt.Fprintf(os.Stderr, "Error: --width must be a positive number of columns.\n")
+		os.Exit(1)
+	}
+	if config.Width == 0 {
+		config.Width = defaultWidth()
+	}
+
+	if layout, ok := namedDateFormats[config.DateFormat]; ok {
+		config.DateFormat = layout
+	}
+	if !validDateLayout(config.DateFormat) {
+		fmt.Fprintf(os.Stderr, "Error: --out-date-format '%s' does not print the day, month and year. Use iso, uk, us or a Go layout such as 2006-01-02.\n", config.DateFormat)
+		os.Exit(1)
+	}
+
+	// See https://no-color.org
+	if os.Getenv("NO_COLOR") != "" {
+		config.NoColor = true
+	}
+	config.Color = !config.NoColor && stdoutIsTerminal()
+	if !localeIsUTF8() {
+		config.ASCII = true
+	}
+
+	if config.JsonOutput && config.CSVOut {
+		fmt.Fprintf(os.Stderr, "Error: --json and --csv-out cannot be used together.\n")
+		os.Exit(1)
+	}
+	if config.YAMLOutput && (config.JsonOutput || config.CSVOut) {
+		fmt.Fprintf(os.Stderr, "Error: --yaml cannot be used with --json or --csv-out.\n")
+		os.Exit(1)
+	}
+
+	switch config.StatusBasis {
+	case "", "current", "worst", "stricter":
+	default:
+		fmt.Fprintf(os.Stderr, "Error: --status-basis must be 'current', 'worst' or 'stricter'.\n")
+		os.Exit(1)
+	}
+
+	if _, err := strconv.Atoi(config.Calendar); config.Calendar != "" && config.Calendar != "all" && (err != nil || len(config.Calendar) != 4) {
+		fmt.Fprintf(os.Stderr, "Error: --calendar must be a year such as 2024, or 'all'.\n")
+		os.Exit(1)
+	}
+	if config.Calendar != "" && (config.JsonOutput || config.YAMLOutput || config.CSVOut) {
+		fmt.Fprintf(os.Stderr, "Error: --calendar is text only and cannot be used with --json, --yaml or --csv-out.\n")
+		os.Exit(1)
+	}
+
+	if config.PersonColumn != "" {
+		switch {
+		case config.Command != "analyze":
+			fmt.Fprintf(os.Stderr, "Error: --person-col is only supported by the analyze command.\n")
+			os.Exit(1)
+		case config.YAMLOutput || config.CSVOut || config.MonthlyJSON || config.Serve != "" || config.Bundle != "" ||
+			config.XLSXOut != "" || config.ICalOut != "",
+			config.JsonOutput && (config.Between != "" || config.TaxYear):
+			fmt.Fprintf(os.Stderr, "Error: --person-col works with the text report and --json only.\n")
+			os.Exit(1)
+		}
+	}
+
+	switch config.Sort {
+	case "", "end", "start", "days", "remaining":
+	default:
+		fmt.Fprintf(os.Stderr, "Error: --sort must be 'end', 'start', 'days' or 'remaining'.\n")
+		os.Exit(1)
+	}
+
+	return config
+}
+
+// namedDateFormats maps the --out-date-format names to Go layouts
+var namedDateFormats = map[string]string{
+	"iso": "2006-01-02",
+	"uk":  "02/01/2006",
+	"us":  "01/02/2006",
+}
+
+// validDateLayout reports whether layout prints a date that parses back to
+// the same day, i.e. it has a day, a month and a year
+func validDateLayout(layout string) bool {
+	sample := time.Date(2006, 11, 23, 0, 0, 0, 0, time.UTC)
+	parsed, err := time.Parse(layout, sample.Format(layout))
+	return err == nil && parsed.Equal(sample)
+}
+
+// defaultWidth returns the report width to use when --width is not set: the
+// terminal width when it is narrower than the usual 90 columns
+func defaultWidth() int {
+	width := terminalWidth()
+	if width <= 0 {
+		width, _ = strconv.Atoi(os.Getenv("COLUMNS"))
+	}
+	if width <= 0 || width > 90 {
+		return 90
+	}
+	return width
+}
+
+// flagValueCount returns how many command-line args the flag named by arg
+// takes as its value
+func flagValueCount(arg string) int {
+	if strings.TrimLeft(arg, "-") == "between" {
+		return 2
+	}
+	return 1
+}
+
+// isBoolFlag reports whether arg names a boolean flag registered in fs
+func isBoolFlag(fs *flag.FlagSet, arg string) bool {
+	f := fs.Lookup(strings.TrimLeft(arg, "-"))
+	if f == nil {
+		return false
+	}
+	bf, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && bf.IsBoolFlag()
+}
+
+// parseTargetDate returns the --date value, or now (in the --tz zone) when
+// it is not set. With --midnight (the default) the result is rounded down to
+// midnight so that window bounds fall on day boundaries regardless of the
+// time of day.
+func parseTargetDate(config Config) (time.Time, error) {
+	targetDate := now(config)
+	if config.Location != nil {
+		targetDate = targetDate.In(config.Location)
+	}
+	if config.CustomDate != "" {
+		var err error
+		targetDate, err = absence.ParseDate(localizeDate(config.CustomDate, config))
+		if err != nil {
+			return time.Time{}, errors.New("Invalid date format for --date parameter. Use format: dd.mm.yyyy")
+		}
+	}
+
+	if config.Midnight {
+		targetDate = absence.TruncateToDay(targetDate)
+	}
+	return targetDate, nil
+}
+
+// now returns the pinned config.Now, or the current time
+func now(config Config) time.Time {
+	if config.Now.IsZero() {
+		return time.Now()
+	}
+	return config.Now
+}
+
+// resolveTargetDate is parseTargetDate for the text report, exiting on error
+func resolveTargetDate(config Config) time.Time {
+	targetDate, err := parseTargetDate(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return targetDate
+}
+
+// isHeaderRow checks if a CSV row is likely a header
+func isHeaderRow(row []string) bool {
+	if len(row) < 2 {
+		return false
+	}
+
+	// A date in the first cell means data, even if the second cell is a
+	// note that happens to contain a header keyword
+	if _, _, _, err := absence.ParseDateOrWeek(row[0]); err == nil {
+		return false
+	}
+
+	if hasHeaderKeyword(row) {
+		return true
+	}
+
+	// Check if we can parse the dates - if not, it's likely a header
+	_, err1 := absence.ParseDate(row[0])
+	_, err2 := absence.ParseDate(row[1])
+
+	return err1 != nil || err2 != nil
+}
+
+// hasHeaderKeyword checks if the first two cells contain common header
+// keywords
+func hasHeaderKeyword(row []string) bool {
+	firstCell := strings.ToLower(strings.TrimSpace(row[0]))
+	secondCell := strings.ToLower(strings.TrimSpace(row[1]))
+
+	headerKeywords := []string{"start", "end", "begin", "from", "to", "departure", "arrival", "date"}
+
+	for _, keyword := range headerKeywords {
+		if strings.Contains(firstCell, keyword) || strings.Contains(secondCell, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// isRepeatedHeader checks if a row after the first is another header, as
+// where CSV files were concatenated: the same cells as the file's header,
+// or header keywords with no date in the start column. Rows with a start
+// date are always data.
+func isRepeatedHeader(row, header []string, startCol int) bool {
+	sameCell := func(a, b string) bool {
+		return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+	}
+	if header != nil && slices.EqualFunc(row, header, sameCell) {
+		return true
+	}
+
+	if len(row) < 2 || len(row) <= startCol {
+		return false
+	}
+	if _, _, _, err := absence.ParseDateOrWeek(row[startCol]); err == nil {
+		return false
+	}
+	return hasHeaderKeyword(row)
+}
+
+// localizeDate translates --locale month names in value to English when
+// that makes it a date, leaving other cells such as a destination of
+// "Mars" untouched. With --tz, a timestamp with an offset becomes its
+// calendar date in that zone, and --rounding picks which date a timestamp
+// with a time of day falls on.
+func localizeDate(value string, config Config) string {
+	if config.Location != nil {
+		if t, err := time.Parse(time.RFC3339, strings.TrimSpace(value)); err == nil {
+			return absence.RoundToDay(t.In(config.Location), config.Rounding).Format("2006-01-02")
+		}
+	}
+	if config.Rounding != "" && config.Rounding != absence.Floor {
+		if t, err := absence.ParseTimestamp(value); err == nil {
+			return absence.RoundToDay(t, config.Rounding).Format("2006-01-02")
+		}
+	}
+	translated := absence.TranslateMonths(value, config.Locale)
+	if translated == value {
+		return value
+	}
+	if _, err := absence.ParseDate(translated); err != nil {
+		return value
+	}
+	return translated
+}
+
+// csvDelimiters are the field delimiters --delimiter accepts and detection
+// chooses from, comma first so that it wins ties
+var csvDelimiters = []rune{',', '\t', ';', '|'}
+
+// sniffDelimiter guesses the field delimiter from the first line that is
+// neither blank nor a comment: the candidate that occurs most often, or a
+// comma when none occurs
+func sniffDelimiter(data []byte, comment rune) rune {
+	var line string
+	for _, l := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(l)
+		if line != "" && (comment == 0 || !strings.HasPrefix(l, string(comment))) {
+			break
+		}
+		line = ""
+	}
+
+	best, bestCount := ',', 0
+	for _, delimiter := range csvDelimiters {
+		if count := strings.Count(line, string(delimiter)); count > bestCount {
+			best, bestCount = delimiter, count
+		}
+	}
+	return best
+}
+
+// readTripsFromCSV reads trips from a CSV file, or from stdin when filename
+// is "-", returning the number of data rows skipped because they had no
+// valid date. The skipped rows' line numbers and reasons go to stderr.
+func readTripsFromCSV(filename string, config Config) ([]Trip, int, error) {
+	// "-" reads the CSV from standard input
+	file := os.Stdin
+	if filename != "-" {
+		var err error
+		file, err = os.Open(filename)
+		if err != nil {
+			return nil, 0, err
+		}
+		defer file.Close()
+	}
+
+	var input io.Reader = file
+	if config.Gzip || strings.HasSuffix(strings.ToLower(filename), ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, 0, err
+		}
+		defer gz.Close()
+		input = gz
+	}
+
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return nil, 0, err
+	}
+	return tripsFromCSV(data, filename, config)
+}
+
+// tripsFromCSV parses the trips in CSV data read from filename
+func tripsFromCSV(data []byte, filename string, config Config) ([]Trip, int, error) {
+	// Excel starts UTF-8 exports with a byte order mark, which would
+	// otherwise stick to the first cell
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = config.Delimiter
+	if reader.Comma == 0 {
+		reader.Comma = sniffDelimiter(data, config.Comment)
+	}
+	reader.Comment = config.Comment
+	if config.CSVStrict {
+		// RFC 4180: every record must have the same number of fields as the first
+		reader.FieldsPerRecord = 0
+	} else {
+		// Lenient: tolerate rows with extra or missing trailing columns, stray
+		// quotes inside fields and spaces before an opening quote, as in
+		// exports that quote every field
+		reader.FieldsPerRecord = -1
+		reader.LazyQuotes = true
+		reader.TrimLeadingSpace = true
+	}
+	var trips []Trip
+	var skipped []string
+	var header []string
+	firstRow := true
+	startCol, endCol, destCol, personCol := 0, 1, 2, -1
+
+	// skip records the current row's line number and why it was skipped
+	skip := func(reason string) {
+		line, _ := reader.FieldPos(0)
+		skipped = append(skipped, fmt.Sprintf("%d (%s)", line, reason))
+	}
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		// Trim padding, as in exports that align their columns
+		for i := range row {
+			row[i] = localizeDate(strings.TrimSpace(row[i]), config)
+		}
+
+		// Skip header row if detected, or use it to locate named columns
+		if firstRow {
+			firstRow = false
+			if namedColumns(config) {
+				startCol, endCol, destCol, personCol, err = findColumns(row, config)
+				if err != nil {
+					return nil, 0, err
+				}
+				header = row
+				continue
+			}
+			if config.StartColumn != "" || config.EndColumn != "" || config.DestColumn != "" || config.PersonColumn != "" {
+				// Column numbers need no header, but one whose start cell
+				// is not a date is still skipped
+				startCol, endCol, destCol, personCol, _ = findColumns(row, config)
+				if len(row) <= startCol {
+					continue
+				}
+				if _, _, _, err := absence.ParseDateOrWeek(row[startCol]); err != nil {
+					header = row
+					continue
+				}
+			} else if isHeaderRow(row) {
+				// Keep who took each trip in a file normalize wrote, which
+				// has no Destination column when no trip has one
+				if personCol = personHeader(row); personCol == destCol {
+					destCol = -1
+				}
+				header = row
+				continue
+			}
+		} else if isRepeatedHeader(row, header, startCol) {
+			// Concatenated files repeat their header mid-file
+			continue
+		}
+
+		if len(row) <= startCol {
+			skip(fmt.Sprintf("%d %s", len(row), plural(len(row), "column", "columns")))
+			continue
+		}
+
+		startDate, endDate, week, err := absence.ParseDateOrWeek(row[startCol])
+		if err != nil {
+			// Skip rows with invalid dates, unless strict and the row looks
+			// like a trip rather than a stray note
+			if config.Strict && len(row) >= 2 {
+				line, _ := reader.FieldPos(startCol)
+				return nil, 0, fmt.Errorf("line %d: invalid date in row %q", line, strings.Join(row, ","))
+			}
+			skip("bad date")
+			continue
+		}
+
+		// Rows with a single date are one-day trips, or one-week trips
+		// when the date is an ISO week. An empty or "ongoing" end is a
+		// trip still under way.
+		ongoing := false
+		if len(row) > endCol {
+			if isOngoingEnd(row[endCol]) {
+				endDate, ongoing = ongoingEnd(startDate, config), true
+			} else if _, last, endWeek, err := absence.ParseDateOrWeek(row[endCol]); err == nil {
+				endDate = last
+				week = week || endWeek
+			} else {
+				line, _ := reader.FieldPos(endCol)
+				if config.Strict {
+					return nil, 0, fmt.Errorf("line %d: invalid end date in row %q", line, strings.Join(row, ","))
+				}
+				fmt.Fprintf(os.Stderr, "Warning: %s line %d: invalid end date %q, skipping row\n", filename, line, row[endCol])
+				skip("bad end date")
+				continue
+			}
+		}
 
-	// Check if file exists
-	if _, err := os.Stat(config.Filename); os.IsNotExist(err) {
-		fmt.Fprintf(os.Stderr, "Error: File '%s' not found.\n", config.Filename)
-		os.Exit(1)
-	}
+		// A reversed trip is almost always a typo, and would otherwise
+		// count negative days
+		line, _ := reader.FieldPos(startCol)
+		if endDate.Before(startDate) && config.AutoFixSwapped {
+			startDate, endDate = swappedDates(row[startCol], row[endCol])
+			fmt.Fprintf(os.Stderr, "Note: %s line %d: trip ends before it starts, swapped start and end\n", filename, line)
+		} else if endDate.Before(startDate) {
+			if config.Strict {
+				return nil, 0, fmt.Errorf("line %d: trip ends (%s) before it starts (%s)",
+					line, strings.TrimSpace(row[endCol]), strings.TrimSpace(row[startCol]))
+			}
+			fmt.Fprintf(os.Stderr, "Warning: %s line %d: trip ends before it starts, skipping row\n", filename, line)
+			skip("ends before it starts")
+			continue
+		}
 
-	// Read and parse CSV
-	trips, err := readTripsFromCSV(config.Filename)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading CSV: %v\n", err)
-		os.Exit(1)
+		trip := Trip{
+			Start:   startDate,
+			End:     endDate,
+			ByWeek:  week,
+			Ongoing: ongoing,
+		}
+		trip.Days = absence.TripDays(trip, config.DayCount)
+		if destCol >= 0 && len(row) > destCol {
+			trip.Destination = strings.TrimSpace(row[destCol])
+		}
+		if personCol >= 0 && len(row) > personCol {
+			trip.Person = row[personCol]
+		}
+		trips = append(trips, trip)
 	}
 
-	if len(trips) == 0 {
-		fmt.Fprintf(os.Stderr, "Error: No valid trip data found in '%s'.\n", config.Filename)
-		fmt.Fprintf(os.Stderr, "Expected format: Start date, End date (with or without header)\n")
-		fmt.Fprintf(os.Stderr, "Supported date formats: dd.mm.yyyy, dd/mm/yyyy, yyyy-mm-dd, mm/dd/yyyy, etc.\n\n")
-		os.Exit(1)
+	if len(skipped) > 0 {
+		fmt.Fprintf(os.Stderr, "Skipped lines in %s: %s\n", filename, strings.Join(skipped, ", "))
 	}
+	return trips, len(skipped), nil
+}
 
-	// Sort trips by end date, then by start date as a tiebreaker so that
-	// the order is deterministic when two trips share the same end date.
-	sort.Slice(trips, func(i, j int) bool {
-		if trips[i].End.Equal(trips[j].End) {
-			return trips[i].Start.Before(trips[j].Start)
-		}
-		return trips[i].End.Before(trips[j].End)
-	})
-
-	if config.JsonOutput {
-		outputJSON(trips, config)
-	} else {
-		// Display per-trip analysis
-		displayTripAnalysis(trips, config)
+// swappedDates returns the dates of a trip whose start and end cells are
+// the wrong way round: from the first day of endCell to the last day of
+// startCell, so that ISO weeks still cover whole weeks
+func swappedDates(startCell, endCell string) (start, end time.Time) {
+	_, end, _, _ = absence.ParseDateOrWeek(startCell)
+	start, _, _, _ = absence.ParseDateOrWeek(endCell)
+	return start, end
+}
 
-		// Display current/estimated status
-		displayCurrentStatus(trips, config)
-	}
+// isOngoingEnd reports whether an end date cell marks a trip that has not
+// ended yet: empty, or "ongoing"
+func isOngoingEnd(value string) bool {
+	value = strings.TrimSpace(value)
+	return value == "" || strings.EqualFold(value, "ongoing")
 }
 
-// parseArgs parses command-line arguments
-func parseArgs() Config {
-	config := Config{
-		WindowMonths: 12,
-		AbsenceLimit: 180,
-	}
+// ongoingEnd is the end of a trip still under way: the target date, or its
+// start when the target date is before it
+func ongoingEnd(start time.Time, config Config) time.Time {
+	return maxTime(start, absence.TruncateToDay(resolveTargetDate(config)))
+}
 
-	// Create a new FlagSet to allow flags after positional arguments
-	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	customDate := fs.String("date", "", "Use a specific date for calculation instead of today (format: dd.mm.yyyy)")
-	windowMonths := fs.Int("window", 12, "Rolling window period in months")
-	absenceLimit := fs.Int("limit", 180, "Maximum allowed absence days in window")
-	jsonOutput := fs.Bool("json", false, "Output results as JSON")
+// findColumns returns the indexes of the --start-column, --end-column,
+// destination and --person-col columns, looking names up in header and
+// taking numbers as 1-based column numbers. Without a destination mapping,
+// a header cell named Destination or Country is used, if any, and without
+// --person-col one named Person.
+func findColumns(header []string, config Config) (int, int, int, int, error) {
+	startCol, endCol, destCol, personCol := 0, 1, -1, -1
+
+	for _, col := range []struct {
+		name  string
+		flag  string
+		index *int
+	}{
+		{config.StartColumn, "--start-column", &startCol},
+		{config.EndColumn, "--end-column", &endCol},
+		{config.DestColumn, "--columns destination", &destCol},
+		{config.PersonColumn, "--person-col", &personCol},
+	} {
+		if col.name == "" {
+			continue
+		}
 
-	fs.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Error: CSV file argument is required.\n\n")
-		fmt.Fprintf(os.Stderr, "Usage: %s <csv_file> [options]\n\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "Options:\n")
-		fmt.Fprintf(os.Stderr, "  --date <dd.mm.yyyy>   Use a specific date for calculation instead of today\n")
-		fmt.Fprintf(os.Stderr, "  --window <months>     Rolling window period in months (default: 12)\n")
-		fmt.Fprintf(os.Stderr, "  --limit <days>        Maximum allowed absence days in window (default: 180)\n\n")
-		fmt.Fprintf(os.Stderr, "Examples:\n")
-		fmt.Fprintf(os.Stderr, "  %s trips.csv\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "  %s trips.csv --date 01.01.2026\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "  %s trips.csv --window 24 --limit 365\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "  %s trips.csv --date 01.01.2026 --window 6 --limit 90\n\n", os.Args[0])
-	}
-
-	// Manually separate filename and flags
-	var filename string
-	var flagArgs []string
+		col.name = strings.TrimSpace(col.name)
+		if number, ok := columnNumber(col.name); ok {
+			*col.index = number - 1
+			continue
+		}
+		*col.index = -1
+		for i, cell := range header {
+			if strings.EqualFold(strings.TrimSpace(cell), col.name) {
+				*col.index = i
+				break
+			}
+		}
+		if *col.index < 0 {
+			return 0, 0, 0, 0, fmt.Errorf("%s %q not found in header row", col.flag, col.name)
+		}
+	}
 
-	for i := 1; i < len(os.Args); i++ {
-		arg := os.Args[i]
-		if strings.HasPrefix(arg, "-") {
-			flagArgs = append(flagArgs, arg)
-			// Check if next arg is a flag value (doesn't start with -)
-			if i+1 < len(os.Args) && !strings.HasPrefix(os.Args[i+1], "-") {
-				i++
-				flagArgs = append(flagArgs, os.Args[i])
+	if config.DestColumn == "" {
+		for i, cell := range header {
+			name := strings.ToLower(strings.TrimSpace(cell))
+			if name == "destination" || name == "country" {
+				destCol = i
+				break
 			}
-		} else if filename == "" {
-			filename = arg
 		}
 	}
+	if config.PersonColumn == "" {
+		personCol = personHeader(header)
+	}
 
-	// Parse flags
-	fs.Parse(flagArgs)
+	return startCol, endCol, destCol, personCol, nil
+}
 
-	// Check for filename
-	if filename == "" {
-		fs.Usage()
-		os.Exit(1)
-	}
+// personHeader returns the index of the header cell named Person, as
+// normalize writes it, or -1
+func personHeader(header []string) int {
+	return slices.IndexFunc(header, func(cell string) bool {
+		return strings.EqualFold(strings.TrimSpace(cell), "person")
+	})
+}
 
-	config.Filename = filename
-	config.CustomDate = *customDate
-	config.WindowMonths = *windowMonths
-	config.AbsenceLimit = *absenceLimit
-	config.JsonOutput = *jsonOutput
+// columnNumber parses a 1-based column number
+func columnNumber(value string) (int, bool) {
+	number, err := strconv.Atoi(strings.TrimSpace(value))
+	return number, err == nil && number >= 1
+}
 
-	// Validate window and limit
-	if config.WindowMonths <= 0 {
-		fmt.Fprintf(os.Stderr, "Error: --window must be a positive number of months.\n")
-		os.Exit(1)
-	}
-	if config.AbsenceLimit <= 0 {
-		fmt.Fprintf(os.Stderr, "Error: --limit must be a positive number of days.\n")
-		os.Exit(1)
+// namedColumns reports whether any column mapping is a header name rather
+// than a column number, so the first row must be a header
+func namedColumns(config Config) bool {
+	for _, name := range []string{config.StartColumn, config.EndColumn, config.DestColumn, config.PersonColumn} {
+		if _, ok := columnNumber(name); name != "" && !ok {
+			return true
+		}
 	}
-
-	return config
+	return false
 }
 
-// parseDate attempts to parse a date string with multiple formats
-func parseDate(dateStr string) (time.Time, error) {
-	dateStr = strings.TrimSpace(dateStr)
+// filterByDateRange drops trips entirely outside --from (or
+// --residence-start, whichever is later) and --to, and clips trips that
+// straddle either date, so only days inside the range count
+func filterByDateRange(trips []Trip, config Config) []Trip {
+	from := maxTime(config.From, config.ResidenceStart)
+	if from.IsZero() && config.To.IsZero() {
+		return trips
+	}
 
-	for _, format := range dateFormats {
-		if t, err := time.Parse(format, dateStr); err == nil {
-			return t, nil
+	var kept []Trip
+	for _, trip := range trips {
+		if !from.IsZero() && trip.Start.Before(from) {
+			trip.Start, trip.Clipped = from, true
+		}
+		if !config.To.IsZero() && trip.End.After(config.To) {
+			trip.End, trip.Clipped = config.To, true
 		}
+		if trip.End.Before(trip.Start) {
+			continue
+		}
+		trip.Days = absence.TripDays(trip, config.DayCount)
+		kept = append(kept, trip)
 	}
-
-	return time.Time{}, fmt.Errorf("unable to parse date: %s", dateStr)
+	return kept
 }
 
-// isHeaderRow checks if a CSV row is likely a header
-func isHeaderRow(row []string) bool {
-	if len(row) < 2 {
-		return false
+// excludeTrips marks same-day trips (with --same-day-counts=false) and trips
+// shorter than --exclude-shorter-than days as excluded, so they stay in the
+// table but count no days
+func excludeTrips(trips []Trip, config Config) []Trip {
+	for i, trip := range trips {
+		switch {
+		case !config.SameDayCounts && trip.Start.Equal(trip.End) && !trip.Ongoing:
+			trips[i].Excluded = "same-day trip"
+		case trip.Days < config.ExcludeShorterThan:
+			trips[i].Excluded = fmt.Sprintf("shorter than %d %s", config.ExcludeShorterThan, plural(config.ExcludeShorterThan, "day", "days"))
+		}
 	}
+	return trips
+}
 
-	// Check if first two cells contain common header keywords
-	firstCell := strings.ToLower(strings.TrimSpace(row[0]))
-	secondCell := strings.ToLower(strings.TrimSpace(row[1]))
-
-	headerKeywords := []string{"start", "end", "begin", "from", "to", "departure", "arrival", "date"}
+// filterByCountries keeps only trips whose destination is in --countries,
+// when --count-mode is "countries", and drops trips to --exclude-countries
+func filterByCountries(trips []Trip, config Config) []Trip {
+	if config.CountMode != "countries" && len(config.ExcludeCountries) == 0 {
+		return trips
+	}
 
-	for _, keyword := range headerKeywords {
-		if strings.Contains(firstCell, keyword) || strings.Contains(secondCell, keyword) {
-			return true
+	var kept []Trip
+	for _, trip := range trips {
+		// Hypothetical trips have no destination but are always meant to count
+		if trip.Projected {
+			kept = append(kept, trip)
+			continue
+		}
+		if config.CountMode == "countries" && !containsFold(config.Countries, trip.Destination) {
+			continue
+		}
+		if containsFold(config.ExcludeCountries, trip.Destination) {
+			continue
 		}
+		kept = append(kept, trip)
 	}
+	return kept
+}
 
-	// Check if we can parse the dates - if not, it's likely a header
-	_, err1 := parseDate(row[0])
-	_, err2 := parseDate(row[1])
-
-	return err1 != nil || err2 != nil
+// containsFold reports whether list contains value, ignoring case
+func containsFold(list []string, value string) bool {
+	return slices.ContainsFunc(list, func(item string) bool {
+		return strings.EqualFold(item, value)
+	})
 }
 
-// readTripsFromCSV reads trips from a CSV file
-func readTripsFromCSV(filename string) ([]Trip, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, err
+// splitList splits a comma-separated flag value, dropping blank items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
-	defer file.Close()
+	return items
+}
 
-	reader := csv.NewReader(file)
+// readTripsFromFiles reads and concatenates trips from several CSV files.
+// Each file is read independently so that its own header row is detected
+// and skipped, rather than being parsed as data mid-stream. A trip with the
+// same start and end as one from an earlier file is read only once.
+func readTripsFromFiles(filenames []string, config Config) ([]Trip, int, error) {
 	var trips []Trip
-	firstRow := true
-
-	for {
-		row, err := reader.Read()
-		if err == io.EOF {
-			break
+	skipped := 0
+	seen := map[[2]time.Time]bool{}
+
+	for _, filename := range filenames {
+		read := readTripsFromCSV
+		if isJSONInput(filename, config) {
+			read = readTripsFromJSON
+		} else if isXLSXInput(filename, config) {
+			read = readTripsFromXLSX
 		}
+		fileTrips, fileSkipped, err := read(filename, config)
 		if err != nil {
-			return nil, err
-		}
-
-		if len(row) < 2 {
-			continue
+			return nil, 0, fmt.Errorf("%s: %w", filename, err)
 		}
 
-		// Skip header row if detected
-		if firstRow {
-			firstRow = false
-			if isHeaderRow(row) {
+		// Duplicates within one file are left for the overlap warnings
+		duplicates := 0
+		for _, trip := range fileTrips {
+			if seen[[2]time.Time{trip.Start, trip.End}] {
+				duplicates++
 				continue
 			}
+			trips = append(trips, trip)
 		}
-
-		startDate, err1 := parseDate(row[0])
-		endDate, err2 := parseDate(row[1])
-
-		if err1 != nil || err2 != nil {
-			// Skip rows with invalid dates
-			continue
+		for _, trip := range fileTrips {
+			seen[[2]time.Time{trip.Start, trip.End}] = true
 		}
-
-		// Calculate days (inclusive)
-		days := int(endDate.Sub(startDate).Hours()/24) + 1
-
-		trips = append(trips, Trip{
-			Start: startDate,
-			End:   endDate,
-			Days:  days,
-		})
+		if duplicates > 0 {
+			fmt.Fprintf(os.Stderr, "Note: %s: skipped %d %s already read from another file\n",
+				filename, duplicates, plural(duplicates, "trip", "trips"))
+		}
+		skipped += fileSkipped
 	}
 
-	return trips, nil
+	return trips, skipped, nil
 }
 
-// addMonths adds months to a date
-func addMonths(t time.Time, months int) time.Time {
-	year, month, day := t.Date()
-	month += time.Month(months)
-
-	// Normalize year and month
-	for month > 12 {
-		month -= 12
-		year++
+// readTrips reads the trips from the --api-url if set, otherwise from the
+// CSV files, then adds any --add-trip trips
+func readTrips(config Config) ([]Trip, int, error) {
+	read := func() ([]Trip, int, error) {
+		return readTripsFromFiles(config.Filenames, config)
 	}
-	for month < 1 {
-		month += 12
-		year--
+	if config.APIURL != "" {
+		read = func() ([]Trip, int, error) {
+			return readTripsFromAPI(config.APIURL, config)
+		}
+	} else if config.Interactive {
+		read = func() ([]Trip, int, error) {
+			return readTripsInteractive(os.Stdin, os.Stderr, config)
+		}
 	}
 
-	// Handle day overflow (e.g., Jan 31 - 1 month = Dec 31, not Dec 30)
-	maxDay := time.Date(year, month+1, 0, 0, 0, 0, 0, t.Location()).Day()
-	if day > maxDay {
-		day = maxDay
+	trips, skipped, err := read()
+	if err != nil {
+		return nil, 0, err
 	}
-
-	return time.Date(year, month, day, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	for _, trip := range config.ProjectedTrips {
+		trip.Days = absence.TripDays(trip, config.DayCount)
+		trips = append(trips, trip)
+	}
+	return trips, skipped, nil
 }
 
-// calculateDaysInWindow calculates total days in a rolling window ending on endDate
-func calculateDaysInWindow(trips []Trip, windowStart, windowEnd time.Time) int {
-	totalDays := 0
+// averageDaysPerMonth returns the average days abroad per month over the
+// rolling window, a measure of travel pace independent of the limit
+func averageDaysPerMonth(totalDaysOutside int, config Config) float64 {
+	if config.WindowDays > 0 {
+		return float64(totalDaysOutside) / (float64(config.WindowDays) / daysPerMonth)
+	}
+	return float64(totalDaysOutside) / float64(config.WindowMonths)
+}
 
-	for _, trip := range trips {
-		// Check if trip overlaps with window
-		if trip.End.Before(windowStart) || trip.Start.After(windowEnd) {
-			continue
-		}
+// percentUsed returns daysInWindow as a percentage of the limit, above 100
+// once the limit is exceeded
+func percentUsed(daysInWindow int, config Config) float64 {
+	return float64(daysInWindow) / float64(config.AbsenceLimit) * 100
+}
 
-		// Calculate overlap
-		overlapStart := maxTime(trip.Start, windowStart)
-		overlapEnd := minTime(trip.End, windowEnd)
+// daysPerMonth is the average length of a month in the Gregorian calendar
+const daysPerMonth = 365.2425 / 12
 
-		// Calculate days in overlap (inclusive)
-		daysInOverlap := int(overlapEnd.Sub(overlapStart).Hours()/24) + 1
+// windowLength describes the window length, e.g. "12 months" or "180 days"
+func windowLength(config Config) string {
+	if config.WindowDays > 0 {
+		return fmt.Sprintf("%d days", config.WindowDays)
+	}
+	return fmt.Sprintf("%d months", config.WindowMonths)
+}
 
-		totalDays += daysInOverlap
+// windowLabel is the window length as an adjective, e.g. "12-month"
+func windowLabel(config Config) string {
+	if config.WindowDays > 0 {
+		return fmt.Sprintf("%d-day", config.WindowDays)
 	}
+	return fmt.Sprintf("%d-month", config.WindowMonths)
+}
 
-	return totalDays
+// windowAbbrev is the short window length for table headers, e.g. "12mo"
+func windowAbbrev(config Config) string {
+	if config.WindowDays > 0 {
+		return fmt.Sprintf("%dd", config.WindowDays)
+	}
+	return fmt.Sprintf("%dmo", config.WindowMonths)
 }
 
 // maxTime returns the later of two times
@@ -315,194 +1639,342 @@ func minTime(a, b time.Time) time.Time {
 	return b
 }
 
-// outputJSON outputs results as JSON
-func outputJSON(trips []Trip, config Config) {
-	type jsonTrip struct {
-		Start        string `json:"start"`
-		End          string `json:"end"`
-		Days         int    `json:"days"`
-		DaysInWindow int    `json:"daysInWindow"`
-		DaysRemaining int   `json:"daysRemaining"`
+// displayTripAnalysis displays per-trip analysis
+func displayTripAnalysis(trips []Trip, config Config) {
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", config.Width))
+	title := fmt.Sprintf("%d-Month", config.WindowMonths)
+	if config.WindowDays > 0 {
+		title = fmt.Sprintf("%d-Day", config.WindowDays)
 	}
-
-	type jsonStatus struct {
-		TargetDate       string `json:"targetDate"`
-		LastTripEnd      string `json:"lastTripEnd"`
-		DaysSinceLastTrip int   `json:"daysSinceLastTrip"`
-		WindowStart      string `json:"windowStart"`
-		WindowEnd        string `json:"windowEnd"`
-		TotalDaysOutside int    `json:"totalDaysOutside"`
-		DaysRemaining    int    `json:"daysRemaining"`
-		Status           string `json:"status"`
+	fmt.Printf("UK ABSENCE CALCULATOR - Rolling %s Window Analysis\n", title)
+	fmt.Println(strings.Repeat("=", config.Width))
+	fmt.Println()
+	fmt.Printf("Allowed absence: %d days in any rolling %s period\n", config.AbsenceLimit, windowLabel(config))
+	if config.CountMode == "countries" {
+		fmt.Printf("Counting only trips to: %s\n", strings.Join(config.Countries, ", "))
+	}
+	if len(config.ExcludeCountries) > 0 {
+		fmt.Printf("Not counting trips to: %s\n", strings.Join(config.ExcludeCountries, ", "))
+	}
+	if !config.ResidenceStart.IsZero() {
+		fmt.Printf("Residence start: %s (days abroad before it don't count)\n", config.ResidenceStart.Format(config.DateFormat))
+	}
+	fmt.Println()
+	fmt.Println(strings.Repeat("-", config.Width))
+	fmt.Printf("%-12s | %-12s | %-6s | %-20s | %-14s | %-6s",
+		"Trip Start", "Trip End", "Days", fmt.Sprintf("Days in %s Window", windowAbbrev(config)), "Days Remaining", "% Used")
+	if config.Verbose {
+		fmt.Printf(" | %-12s | %s", "Window Start", "Window End")
 	}
+	fmt.Println()
+	fmt.Println(strings.Repeat("-", config.Width))
 
-	type jsonOutput struct {
-		Config struct {
-			WindowMonths int `json:"windowMonths"`
-			AbsenceLimit int `json:"absenceLimit"`
-		} `json:"config"`
-		Trips  []jsonTrip `json:"trips"`
-		Status jsonStatus `json:"status"`
+	result := absence.Analyze(trips, resolveTargetDate(config), config.Config)
+
+	rows := result.Trips
+	if config.ActiveOnly {
+		rows = activeRows(rows, result.WindowStart, result.TargetDate)
 	}
+	for _, row := range sortRows(rows, config.Sort) {
+		trip, remainingDays := row.Trip, row.DaysRemaining
 
-	var output jsonOutput
-	output.Config.WindowMonths = config.WindowMonths
-	output.Config.AbsenceLimit = config.AbsenceLimit
+		status := absence.Status(remainingDays, config.Config)
+		fmt.Print(colorize(fmt.Sprintf("%-12s | %-12s | %6d | %20d | %14d | %5.1f%%",
+			trip.Start.Format(config.DateFormat),
+			trip.End.Format(config.DateFormat),
+			trip.Days,
+			row.DaysInWindow,
+			remainingDays,
+			percentUsed(row.DaysInWindow, config)), status, config))
+		if config.Verbose {
+			fmt.Printf(" | %-12s | %s", row.WindowStart.Format(config.DateFormat), row.WindowEnd.Format(config.DateFormat))
+		}
+		if trip.ByWeek {
+			fmt.Print("  [week]")
+		}
+		if trip.Projected {
+			fmt.Print("  [projected]")
+		}
+		if trip.Ongoing {
+			fmt.Print("  [ongoing]")
+		}
+		if trip.Excluded != "" {
+			fmt.Print("  [excluded]")
+		}
+		if trip.Clipped {
+			fmt.Print("  [clipped]")
+		}
+		if config.MaxSingle > 0 && trip.Days > config.MaxSingle {
+			fmt.Print(colorize("  [too long]", "exceeded", config))
+		}
+		fmt.Println()
 
-	// Build trip analysis
-	for _, trip := range trips {
-		windowStart := addMonths(trip.End, -config.WindowMonths)
-		totalDaysInWindow := calculateDaysInWindow(trips, windowStart, trip.End)
-		remainingDays := config.AbsenceLimit - totalDaysInWindow
+		// The destination goes on its own line below the row, cut to --width
+		if trip.Destination != "" {
+			label := trip.Destination
+			if config.ShowFlags && !config.NoColor && !config.ASCII {
+				label = destinationLabel(trip.Destination)
+			}
+			fmt.Printf("%s  %s\n", strings.Repeat(" ", 12), truncateText(label, config.Width-14))
+		}
 
-		output.Trips = append(output.Trips, jsonTrip{
-			Start:        trip.Start.Format("02.01.2006"),
-			End:          trip.End.Format("02.01.2006"),
-			Days:         trip.Days,
-			DaysInWindow: totalDaysInWindow,
-			DaysRemaining: remainingDays,
-		})
+		// Warning if over limit
+		if remainingDays < 0 {
+			fmt.Println(colorize(fmt.Sprintf("%s %s WARNING: Exceeded %d-day limit by %d days!",
+				strings.Repeat(" ", 12), warnMark(config), config.AbsenceLimit, int(math.Abs(float64(remainingDays)))), status, config))
+		}
 	}
 
-	// Build status
-	var targetDate time.Time
-	if config.CustomDate != "" {
-		var err error
-		targetDate, err = parseDate(config.CustomDate)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: Invalid date format for --date parameter.\n")
-			os.Exit(1)
-		}
+	fmt.Println(strings.Repeat("-", config.Width))
+	if hidden := len(result.Trips) - len(rows); hidden > 0 {
+		fmt.Printf("%d %s outside the current window not shown (--active-only).\n",
+			hidden, plural(hidden, "trip", "trips"))
+	}
+	anchor := "end"
+	if config.Anchor == absence.AnchorStart {
+		anchor = "start"
+	}
+	if config.WindowDays > 0 {
+		fmt.Printf("\nNote: The %s window ends on each trip's %s date and includes it.\n", windowLabel(config), anchor)
 	} else {
-		targetDate = time.Now()
+		fmt.Printf("\nNote: The %d-month window ends on each trip's %s date and starts %d months before.\n",
+			config.WindowMonths, anchor, config.WindowMonths)
 	}
-
-	windowStart := addMonths(targetDate, -config.WindowMonths)
-	lastTrip := trips[len(trips)-1]
-	daysInUK := int(targetDate.Sub(lastTrip.End).Hours() / 24)
-	totalDaysOutside := calculateDaysInWindow(trips, windowStart, targetDate)
-	remainingDays := config.AbsenceLimit - totalDaysOutside
-	warningThreshold := int(math.Min(30, math.Ceil(float64(config.AbsenceLimit)*0.15)))
-
-	statusStr := "ok"
-	if remainingDays < 0 {
-		statusStr = "exceeded"
-	} else if remainingDays < warningThreshold {
-		statusStr = "caution"
-	}
-
-	output.Status = jsonStatus{
-		TargetDate:       targetDate.Format("02.01.2006"),
-		LastTripEnd:      lastTrip.End.Format("02.01.2006"),
-		DaysSinceLastTrip: daysInUK,
-		WindowStart:      windowStart.Format("02.01.2006"),
-		WindowEnd:        targetDate.Format("02.01.2006"),
-		TotalDaysOutside: totalDaysOutside,
-		DaysRemaining:    remainingDays,
-		Status:           statusStr,
-	}
-
-	encoder := json.NewEncoder(os.Stdout)
-	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(output); err != nil {
-		fmt.Fprintf(os.Stderr, "Error encoding JSON: %v\n", err)
-		os.Exit(1)
+	switch config.DayCount {
+	case absence.Exclusive:
+		fmt.Println("Departure and return days are not counted, so each trip counts 2 days fewer.")
+	case absence.DepartureOnly:
+		fmt.Println("Return days are not counted, so each trip counts 1 day fewer.")
+	}
+	if config.ExcludeAnchorTrip {
+		fmt.Println("Days in window include only prior trips; each row's own trip is excluded.")
+	} else {
+		fmt.Println("Days in window include all days from trips that overlap with that window.")
 	}
-}
-
-// displayTripAnalysis displays per-trip analysis
-func displayTripAnalysis(trips []Trip, config Config) {
-	fmt.Println()
-	fmt.Println(strings.Repeat("=", 90))
-	fmt.Printf("UK ABSENCE CALCULATOR - Rolling %d-Month Window Analysis\n", config.WindowMonths)
-	fmt.Println(strings.Repeat("=", 90))
-	fmt.Println()
-	fmt.Printf("Allowed absence: %d days in any rolling %d-month period\n\n", config.AbsenceLimit, config.WindowMonths)
-	fmt.Println(strings.Repeat("-", 90))
-	fmt.Printf("%-12s | %-12s | %-6s | %-20s | %-12s\n",
-		"Trip Start", "Trip End", "Days", fmt.Sprintf("Days in %dmo Window", config.WindowMonths), "Days Remaining")
-	fmt.Println(strings.Repeat("-", 90))
-
 	for _, trip := range trips {
-		windowStart := addMonths(trip.End, -config.WindowMonths)
-		totalDaysInWindow := calculateDaysInWindow(trips, windowStart, trip.End)
-		remainingDays := config.AbsenceLimit - totalDaysInWindow
-
-		fmt.Printf("%-12s | %-12s | %6d | %20d | %12d\n",
-			trip.Start.Format("02.01.2006"),
-			trip.End.Format("02.01.2006"),
-			trip.Days,
-			totalDaysInWindow,
-			remainingDays)
-
-		// Warning if over limit
-		if remainingDays < 0 {
-			fmt.Printf("%s ⚠️  WARNING: Exceeded %d-day limit by %d days!\n",
-				strings.Repeat(" ", 12), config.AbsenceLimit, int(math.Abs(float64(remainingDays))))
+		if trip.ByWeek {
+			fmt.Println("Trips marked [week] were given as ISO weeks and count Monday to Sunday.")
+			break
+		}
+	}
+	for _, trip := range trips {
+		if trip.Projected {
+			fmt.Println("Trips marked [projected] are hypothetical trips added with --add-trip.")
+			break
+		}
+	}
+	if _, ok := ongoingTrip(trips); ok {
+		fmt.Printf("Trips marked [ongoing] have no end date yet and count up to %s.\n",
+			absence.TruncateToDay(resolveTargetDate(config)).Format(config.DateFormat))
+	}
+	var reasons []string
+	for _, trip := range trips {
+		if trip.Excluded != "" && !slices.Contains(reasons, trip.Excluded) {
+			reasons = append(reasons, trip.Excluded)
+		}
+	}
+	if len(reasons) > 0 {
+		fmt.Printf("Trips marked [excluded] count no days (%s).\n", strings.Join(reasons, "; "))
+	}
+	for _, trip := range trips {
+		if trip.Clipped {
+			fmt.Println("Trips marked [clipped] are cut to the date range (--from, --to, --residence-start) and count only their days inside it.")
+			break
 		}
 	}
+	if long := longTrips(trips, config); len(long) > 0 {
+		fmt.Printf("Trips marked [too long] exceed the %d-day limit on a single absence (--max-single): %d %s.\n",
+			config.MaxSingle, len(long), plural(len(long), "trip", "trips"))
+	}
+	fmt.Println()
 
-	fmt.Println(strings.Repeat("-", 90))
-	fmt.Printf("\nNote: The %d-month window ends on each trip's end date and starts %d months before.\n",
-		config.WindowMonths, config.WindowMonths)
-	fmt.Println("Days in window include all days from trips that overlap with that window.\n")
+	// The chart is for reading at a terminal, not for logs or pipes
+	if config.Chart && stdoutIsTerminal() {
+		displayChart(rows, config)
+	}
 }
 
 // displayCurrentStatus displays current or estimated status
 func displayCurrentStatus(trips []Trip, config Config) {
-	fmt.Println(strings.Repeat("=", 90))
+	fmt.Println(strings.Repeat("=", config.Width))
 
-	var targetDate time.Time
-	var err error
+	targetDate := resolveTargetDate(config)
 
 	if config.CustomDate != "" {
-		targetDate, err = parseDate(config.CustomDate)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: Invalid date format for --date parameter. Use format: dd.mm.yyyy\n")
-			os.Exit(1)
-		}
-		fmt.Printf("ESTIMATED STATUS - As of %s\n", targetDate.Format("02.01.2006"))
+		fmt.Printf("ESTIMATED STATUS - As of %s\n", targetDate.Format(config.DateFormat))
 	} else {
-		targetDate = time.Now()
 		fmt.Println("CURRENT STATUS - As of Today")
 	}
 
-	fmt.Println(strings.Repeat("=", 90))
+	fmt.Println(strings.Repeat("=", config.Width))
 	fmt.Println()
 
-	windowStart := addMonths(targetDate, -config.WindowMonths)
+	result := absence.Analyze(trips, targetDate, config.Config)
 	lastTrip := trips[len(trips)-1]
-	daysInUK := int(targetDate.Sub(lastTrip.End).Hours() / 24)
 
 	if config.CustomDate != "" {
-		fmt.Printf("Estimated date: %s\n", targetDate.Format("02.01.2006"))
+		fmt.Printf("Estimated date: %s\n", targetDate.Format(config.DateFormat))
 	} else {
-		fmt.Printf("Today's date: %s\n", targetDate.Format("02.01.2006"))
+		fmt.Printf("Today's date: %s\n", targetDate.Format(config.DateFormat))
 	}
-	fmt.Printf("Last trip ended: %s\n", lastTrip.End.Format("02.01.2006"))
-	fmt.Printf("Days in UK since last trip: %d days\n", daysInUK)
-	fmt.Printf("Rolling %d-month window: %s to %s\n\n",
-		config.WindowMonths, windowStart.Format("02.01.2006"), targetDate.Format("02.01.2006"))
-
-	totalDaysOutside := calculateDaysInWindow(trips, windowStart, targetDate)
-	remainingDays := config.AbsenceLimit - totalDaysOutside
+	if first := firstTripStart(trips); absence.TruncateToDay(targetDate).Before(first) {
+		fmt.Printf("Note: This date is before your first trip (%s), so no trips count yet.\n", first.Format(config.DateFormat))
+	}
+	if ongoing, ok := ongoingTrip(trips); ok {
+		fmt.Printf("Currently abroad since %s\n", ongoing.Start.Format(config.DateFormat))
+	} else if daysInUK, ok := daysSinceLastTrip(trips, targetDate); ok {
+		fmt.Printf("Last trip ended: %s\n", lastTrip.End.Format(config.DateFormat))
+		fmt.Printf("Days in UK since last trip: %d days\n", daysInUK)
+	} else if trip, away, ok := pendingReturn(trips, targetDate); ok && away {
+		days := absence.DaysBetween(targetDate, trip.End)
+		fmt.Printf("Currently abroad until %s (back in %d %s)\n",
+			trip.End.Format(config.DateFormat), days, plural(days, "day", "days"))
+	} else if ok {
+		days := absence.DaysBetween(targetDate, trip.Start)
+		fmt.Printf("Next abroad from %s until %s (leaving in %d %s)\n",
+			trip.Start.Format(config.DateFormat), trip.End.Format(config.DateFormat), days, plural(days, "day", "days"))
+	}
+	fmt.Printf("Rolling %s window: %s to %s\n\n",
+		windowLabel(config), result.WindowStart.Format(config.DateFormat), targetDate.Format(config.DateFormat))
 
-	// Calculate warning threshold (15% of limit or 30 days, whichever is smaller)
-	warningThreshold := int(math.Min(30, math.Ceil(float64(config.AbsenceLimit)*0.15)))
+	totalDaysOutside := result.TotalDaysOutside
+	remainingDays := result.DaysRemaining
 
-	fmt.Println(strings.Repeat("-", 90))
-	fmt.Printf("Days spent outside UK (last %d months): %d days\n", config.WindowMonths, totalDaysOutside)
+	fmt.Println(strings.Repeat("-", config.Width))
+	fmt.Printf("Days spent outside UK (last %s): %d days\n", windowLength(config), totalDaysOutside)
 	fmt.Printf("Days remaining (out of %d):            %d days\n", config.AbsenceLimit, remainingDays)
-	fmt.Println(strings.Repeat("-", 90))
+	if config.ShowAverage {
+		fmt.Printf("Average: %.1f days/month abroad\n", averageDaysPerMonth(totalDaysOutside, config))
+	}
 
-	if remainingDays < 0 {
-		fmt.Printf("\n⚠️  WARNING: You have EXCEEDED the %d-day limit by %d days!\n",
-			config.AbsenceLimit, int(math.Abs(float64(remainingDays))))
-	} else if remainingDays < warningThreshold {
-		fmt.Printf("\n⚠️  CAUTION: You have less than %d days remaining in your allowance.\n", warningThreshold)
-	} else {
-		fmt.Printf("\n✓ You are within the %d-day limit.\n", config.AbsenceLimit)
+	// Officers can assess any window, so show the worst one on record too
+	dash := "–"
+	if config.ASCII {
+		dash = "-"
+	}
+	fmt.Printf("Peak absence: %d days (window %s%s%s)\n", result.PeakDaysOutside,
+		result.PeakWindowStart.Format(config.DateFormat), dash, result.PeakWindowEnd.Format(config.DateFormat))
+
+	maxStay, unlimitedStay := maxContinuousStay(trips, targetDate, config)
+	fmt.Println(maxStayLine(maxStay, unlimitedStay, targetDate, config))
+	if date, days, ok := nextRelease(trips, targetDate, config); ok {
+		fmt.Printf("Days freed next: %d days on %s, as the oldest trip leaves the window\n", days, date.Format(config.DateFormat))
+	}
+	fmt.Println("If you stay put:")
+	for _, point := range stayPutProjection(trips, targetDate, config) {
+		label := fmt.Sprintf("+%d %s", point.Months, plural(point.Months, "month", "months"))
+		fmt.Printf("  %-9s (%s): %d days remaining\n", label, point.Date.Format(config.DateFormat), point.DaysRemaining)
+	}
+	fmt.Println(strings.Repeat("-", config.Width))
+
+	if config.Verbose {
+		_, contributions := absence.WindowContributions(trips, result.WindowStart, targetDate, config.DayCount)
+		fmt.Println("Trips in this window:")
+		for _, c := range contributions {
+			fmt.Printf("  %s to %s: %d days\n",
+				c.Trip.Start.Format(config.DateFormat), c.Trip.End.Format(config.DateFormat), c.Days)
+		}
+		if len(contributions) == 0 {
+			fmt.Println("  (none)")
+		}
+		fmt.Println(strings.Repeat("-", config.Width))
 	}
 
+	if config.SafeTravelDays > 0 {
+		if start, ok := nextSafeTravelDate(trips, targetDate, config.SafeTravelDays, config); ok {
+			fmt.Printf("Next safe start for a %d-day trip: %s\n", config.SafeTravelDays, start.Format(config.DateFormat))
+		} else {
+			fmt.Printf("A %d-day trip is never safe: on its own it exceeds the %d-day limit in %s.\n",
+				config.SafeTravelDays, config.AbsenceLimit, windowLength(config))
+		}
+	}
+
+	fmt.Printf("\n%s\n", statusLine(trips, targetDate, remainingDays, config))
+
 	fmt.Println()
+	displayHistorySummary(trips, config)
+	fmt.Println()
+}
+
+// daysSinceLastTrip returns the days from the end of the last trip to
+// targetDate. ok is false, and days 0, when the last trip ends after
+// targetDate, as happens with a --date in the past.
+func daysSinceLastTrip(trips []Trip, targetDate time.Time) (days int, ok bool) {
+	days = absence.DaysBetween(trips[len(trips)-1].End, targetDate)
+	if days < 0 {
+		return 0, false
+	}
+	return days, true
+}
+
+// pendingReturn returns the trip that keeps the last trip from having ended
+// by targetDate: the one under way on targetDate (away true), or else the
+// next one to start after it. ok is false when every trip has ended.
+func pendingReturn(trips []Trip, targetDate time.Time) (trip Trip, away, ok bool) {
+	targetDate = absence.TruncateToDay(targetDate)
+	for _, t := range trips {
+		if !t.Start.After(targetDate) && t.End.After(targetDate) {
+			return t, true, true
+		}
+	}
+	for _, t := range trips {
+		if t.Start.After(targetDate) && (!ok || t.Start.Before(trip.Start)) {
+			trip, ok = t, true
+		}
+	}
+	return trip, false, ok
+}
+
+// ongoingTrip returns the trip still under way, if any
+func ongoingTrip(trips []Trip) (Trip, bool) {
+	for _, trip := range trips {
+		if trip.Ongoing {
+			return trip, true
+		}
+	}
+	return Trip{}, false
+}
+
+// firstTripStart returns the earliest start date of the trips
+func firstTripStart(trips []Trip) time.Time {
+	first := trips[0].Start
+	for _, trip := range trips {
+		first = minTime(first, trip.Start)
+	}
+	return first
+}
+
+// statusLine returns the colorized verdict for the window ending on
+// targetDate with remainingDays left, judged on --status-basis
+func statusLine(trips []Trip, targetDate time.Time, remainingDays int, config Config) string {
+	// Judge the status on the worst window instead when asked to
+	var peakEnd time.Time
+	if config.StatusBasis == "worst" || config.StatusBasis == "stricter" {
+		remainingDays, _, peakEnd = basisStatus(trips, targetDate, config)
+	}
+
+	var message string
+	if remainingDays < 0 && !peakEnd.IsZero() {
+		message = fmt.Sprintf("%s WARNING: Your window ending %s EXCEEDED the %d-day limit by %d days!",
+			warnMark(config), peakEnd.Format(config.DateFormat), config.AbsenceLimit, -remainingDays)
+	} else if remainingDays < 0 {
+		message = fmt.Sprintf("%s WARNING: You have EXCEEDED the %d-day limit by %d days!",
+			warnMark(config), config.AbsenceLimit, int(math.Abs(float64(remainingDays))))
+	} else if remainingDays <= absence.CautionThreshold(config.Config) {
+		message = fmt.Sprintf("%s CAUTION: You have less than %d days remaining in your allowance.", warnMark(config), absence.CautionThreshold(config.Config)+1)
+	} else {
+		message = fmt.Sprintf("%s You are within the %d-day limit.", okMark(config), config.AbsenceLimit)
+	}
+	return colorize(message, absence.Status(remainingDays, config.Config), config)
+}
+
+// displayQuietStatus displays only the window totals and the status line
+func displayQuietStatus(trips []Trip, config Config) {
+	targetDate := resolveTargetDate(config)
+	result := absence.Analyze(trips, targetDate, config.Config)
+
+	fmt.Printf("Days spent outside UK (last %s): %d days\n", windowLength(config), result.TotalDaysOutside)
+	fmt.Printf("Days remaining (out of %d):            %d days\n", config.AbsenceLimit, result.DaysRemaining)
+	fmt.Println(statusLine(trips, targetDate, result.DaysRemaining, config))
 }
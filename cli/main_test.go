@@ -0,0 +1,1572 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"stay-within/absence"
+)
+
+// writeTempCSV writes content to a CSV file in a per-test temp directory
+func writeTempCSV(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadTripsFromFilesSkipsHeaderInEachFile(t *testing.T) {
+	first := writeTempCSV(t, "2023.csv", "Start,End\n25.05.2023,10.08.2023\n15.09.2023,20.09.2023\n")
+	second := writeTempCSV(t, "2024.csv", "Departure,Return\n05.01.2024,15.01.2024\n")
+
+	trips, _, err := readTripsFromFiles([]string{first, second}, Config{})
+	if err != nil {
+		t.Fatalf("readTripsFromFiles: %v", err)
+	}
+
+	if len(trips) != 3 {
+		t.Fatalf("got %d trips, want 3", len(trips))
+	}
+	if got := trips[2].Start.Format("02.01.2006"); got != "05.01.2024" {
+		t.Errorf("first trip of second file starts %s, want 05.01.2024", got)
+	}
+}
+
+func TestReadTripsFromFilesWithoutHeaders(t *testing.T) {
+	first := writeTempCSV(t, "a.csv", "25.05.2023,10.08.2023\n")
+	second := writeTempCSV(t, "b.csv", "05.01.2024,15.01.2024\n")
+
+	trips, _, err := readTripsFromFiles([]string{first, second}, Config{})
+	if err != nil {
+		t.Fatalf("readTripsFromFiles: %v", err)
+	}
+
+	if len(trips) != 2 {
+		t.Fatalf("got %d trips, want 2: the first row of each file is data, not a header", len(trips))
+	}
+}
+
+func TestReadTripsFromFilesDeduplicatesAcrossFiles(t *testing.T) {
+	first := writeTempCSV(t, "2023.csv", "Start,End\n25.05.2023,10.08.2023\n20.12.2023,05.01.2024\n")
+	second := writeTempCSV(t, "2024.csv", "Start,End\n20.12.2023,05.01.2024\n01.03.2024,10.03.2024\n")
+
+	trips, _, err := readTripsFromFiles([]string{first, second}, Config{})
+	if err != nil {
+		t.Fatalf("readTripsFromFiles: %v", err)
+	}
+
+	if len(trips) != 3 {
+		t.Fatalf("got %d trips, want 3: the trip spanning new year is in both files", len(trips))
+	}
+}
+
+func TestReadTripsFromCSVMixedRangesAndSingleDays(t *testing.T) {
+	path := writeTempCSV(t, "mixed.csv", "Start,End\n"+
+		"01.03.2024,10.03.2024\n"+
+		"15.03.2024\n"+
+		"2024-W12\n"+
+		"01.04.2024,03.04.2024\n")
+
+	trips, _, err := readTripsFromCSV(path, Config{})
+	if err != nil {
+		t.Fatalf("readTripsFromCSV: %v", err)
+	}
+
+	wantDays := []int{10, 1, 7, 3}
+	if len(trips) != len(wantDays) {
+		t.Fatalf("got %d trips, want %d", len(trips), len(wantDays))
+	}
+	for i, want := range wantDays {
+		if trips[i].Days != want {
+			t.Errorf("trip %d: got %d days, want %d", i, trips[i].Days, want)
+		}
+	}
+	if !trips[1].Start.Equal(trips[1].End) {
+		t.Errorf("single-date row should start and end on the same day")
+	}
+}
+
+func TestReadTripsFromCSVInvalidEndDate(t *testing.T) {
+	path := writeTempCSV(t, "trips.csv", "Start,End\n01.09.2024,32.09.2024\n01.10.2024,05.10.2024\n20.10.2024,Day trip to Calais\n")
+
+	trips, skipped, err := readTripsFromCSV(path, Config{})
+	if err != nil {
+		t.Fatalf("readTripsFromCSV: %v", err)
+	}
+	if len(trips) != 1 || skipped != 2 || trips[0].Days != 5 {
+		t.Errorf("got %d trips and %d skipped, want the 5-day trip and 2 skipped", len(trips), skipped)
+	}
+
+	_, _, err = readTripsFromCSV(path, Config{Strict: true})
+	if err == nil || !strings.Contains(err.Error(), "line 2") || !strings.Contains(err.Error(), "32.09.2024") {
+		t.Errorf("strict mode error = %v, want one naming line 2 and its values", err)
+	}
+}
+
+func TestReadTripsFromCSVSingleDayFirstRowIsNotHeader(t *testing.T) {
+	path := writeTempCSV(t, "excursions.csv", "20.03.2024\n01.04.2024,03.04.2024\n")
+
+	trips, _, err := readTripsFromCSV(path, Config{})
+	if err != nil {
+		t.Fatalf("readTripsFromCSV: %v", err)
+	}
+	if len(trips) != 2 {
+		t.Fatalf("got %d trips, want 2", len(trips))
+	}
+}
+
+func TestReadTripsFromCSVBOM(t *testing.T) {
+	trips, skipped, err := readTripsFromCSV(filepath.Join("..", "tests", "fixtures", "bom-whitespace.csv"), Config{})
+	if err != nil {
+		t.Fatalf("readTripsFromCSV: %v", err)
+	}
+	if len(trips) != 3 || skipped != 0 {
+		t.Fatalf("got %d trips and %d skipped, want 3 and 0", len(trips), skipped)
+	}
+	if want := time.Date(2023, 5, 25, 0, 0, 0, 0, time.UTC); !trips[0].Start.Equal(want) || trips[0].Days != 78 {
+		t.Errorf("first trip = %+v, want 25.05.2023 to 10.08.2023", trips[0])
+	}
+}
+
+func TestReadTripsFromCSVMessyQuoting(t *testing.T) {
+	path := filepath.Join("..", "tests", "fixtures", "messy-quoting.csv")
+	trips, skipped, err := readTripsFromCSV(path, Config{})
+	if err != nil {
+		t.Fatalf("readTripsFromCSV: %v", err)
+	}
+	// The last row's quote is never closed, so it runs to the end of the
+	// file as one field and is skipped rather than failing the whole file
+	if len(trips) != 3 || skipped != 1 {
+		t.Fatalf("got %d trips and %d skipped, want 3 and 1", len(trips), skipped)
+	}
+	for i, want := range []string{`Côte d"Azur`, `Lyon "Part-Dieu"`, `Bar"celona`} {
+		if trips[i].Destination != want {
+			t.Errorf("trip %d destination = %q, want %q", i, trips[i].Destination, want)
+		}
+	}
+
+	if _, _, err := readTripsFromCSV(path, Config{CSVStrict: true}); err == nil {
+		t.Error("--csv-strict accepted the stray quotes")
+	}
+}
+
+func TestReadTripsFromCSVComments(t *testing.T) {
+	path := writeTempCSV(t, "trips.csv", "# Trips; dates are dd.mm.yyyy\n"+
+		"Start,End\n"+
+		"01.03.2024,10.03.2024\n"+
+		"# Conference, approved absence\n"+
+		"01.04.2024,03.04.2024\n"+
+		"#01.05.2024,05.05.2024\n")
+
+	trips, skipped, err := readTripsFromCSV(path, Config{Comment: '#'})
+	if err != nil {
+		t.Fatalf("readTripsFromCSV: %v", err)
+	}
+	if len(trips) != 2 || skipped != 0 {
+		t.Fatalf("got %d trips and %d skipped, want 2 and 0", len(trips), skipped)
+	}
+
+	// Without a comment character they are rows like any other
+	if _, skipped, _ := readTripsFromCSV(path, Config{}); skipped == 0 {
+		t.Errorf("comment lines ignored without a comment character")
+	}
+}
+
+func TestIsHeaderRow(t *testing.T) {
+	for _, tc := range []struct {
+		row  []string
+		want bool
+	}{
+		{[]string{"Start", "End"}, true},
+		{[]string{"Departure date", "Return date", "Country"}, true},
+		{[]string{"From", "To"}, true},
+		{[]string{"Leaving", "Back"}, true}, // no keyword, but no dates either
+		{[]string{"25.05.2023", "10.08.2023"}, false},
+		{[]string{"2024-W10", "2024-W11"}, false},
+		// A date first is data, even with a note that mentions a keyword
+		{[]string{"02.09.2023", "Day trip to Calais from Dover"}, false},
+		{[]string{"02.09.2023"}, false},
+		{[]string{"Start"}, false},
+	} {
+		if got := isHeaderRow(tc.row); got != tc.want {
+			t.Errorf("isHeaderRow(%q) = %v, want %v", tc.row, got, tc.want)
+		}
+	}
+}
+
+func TestReadTripsFromCSVRepeatedHeader(t *testing.T) {
+	path := writeTempCSV(t, "trips.csv", "Start,End\n"+
+		"01.03.2024,10.03.2024\n"+
+		"start , END\n"+
+		"01.04.2024,03.04.2024\n"+
+		"Departure Date,Return Date\n"+
+		"02.05.2024\n"+
+		"unknown,unknown\n")
+
+	trips, skipped, err := readTripsFromCSV(path, Config{})
+	if err != nil {
+		t.Fatalf("readTripsFromCSV: %v", err)
+	}
+	// Both repeated headers are skipped silently; the day trip is data and
+	// the unknown row is still reported
+	if len(trips) != 3 || skipped != 1 {
+		t.Errorf("got %d trips and %d skipped, want 3 and 1", len(trips), skipped)
+	}
+}
+
+func TestReadTripsFromCSVNamedColumns(t *testing.T) {
+	path := writeTempCSV(t, "export.csv", "ID,Destination,Departure,Return\n"+
+		"1,France,01.03.2024,10.03.2024\n"+
+		"2,Spain,01.04.2024,03.04.2024\n")
+
+	trips, _, err := readTripsFromCSV(path, Config{StartColumn: "departure", EndColumn: "Return"})
+	if err != nil {
+		t.Fatalf("readTripsFromCSV: %v", err)
+	}
+	if len(trips) != 2 || trips[0].Days != 10 || trips[1].Days != 3 {
+		t.Fatalf("got %+v, want trips of 10 and 3 days", trips)
+	}
+
+	if _, _, err := readTripsFromCSV(path, Config{StartColumn: "Leaving"}); err == nil {
+		t.Errorf("expected an error for a missing --start-column header")
+	}
+
+	// Column numbers work with the header, and without one
+	noHeader := writeTempCSV(t, "export.csv", "1,France,01.03.2024,10.03.2024\n"+
+		"2,Spain,01.04.2024,03.04.2024\n")
+	for _, file := range []string{path, noHeader} {
+		trips, _, err := readTripsFromCSV(file, Config{StartColumn: "3", EndColumn: "4", DestColumn: "2"})
+		if err != nil {
+			t.Fatalf("readTripsFromCSV: %v", err)
+		}
+		if len(trips) != 2 || trips[0].Days != 10 || trips[1].Destination != "Spain" {
+			t.Errorf("%s: got %+v, want trips of 10 and 3 days", file, trips)
+		}
+	}
+}
+
+func TestFilterByCountries(t *testing.T) {
+	path := writeTempCSV(t, "trips.csv", "Start,End,Destination\n"+
+		"01.01.2025,10.01.2025,France\n"+
+		"01.02.2025,05.02.2025,USA\n"+
+		"01.03.2025,02.03.2025, spain \n")
+	trips, _, err := readTripsFromCSV(path, Config{})
+	if err != nil {
+		t.Fatalf("readTripsFromCSV: %v", err)
+	}
+
+	all := filterByCountries(trips, Config{CountMode: "abroad"})
+	if len(all) != 3 {
+		t.Errorf("abroad mode kept %d trips, want 3", len(all))
+	}
+
+	kept := filterByCountries(trips, Config{CountMode: "countries", Countries: []string{"france", "Spain"}})
+	if len(kept) != 2 || kept[0].Destination != "France" || kept[1].Destination != "spain" {
+		t.Errorf("countries mode kept %+v, want the France and Spain trips", kept)
+	}
+}
+
+func TestReadTripsFromAPIFollowsNextCursor(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization header = %q, want %q", got, "Bearer secret")
+		}
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			fmt.Fprint(w, `{"data": [{"start": "01.01.2025", "end": "10.01.2025", "country": "France"}, {"start": "bad"}], "next": "page2"}`)
+		case "page2":
+			fmt.Fprint(w, `{"data": [{"start": "2025-03-01"}], "next": null}`)
+		default:
+			t.Errorf("unexpected cursor %q", r.URL.Query().Get("cursor"))
+		}
+	}))
+	defer server.Close()
+
+	trips, skipped, err := readTripsFromAPI(server.URL+"/trips", Config{APIToken: "secret"})
+	if err != nil {
+		t.Fatalf("readTripsFromAPI: %v", err)
+	}
+
+	if len(trips) != 2 || skipped != 1 {
+		t.Fatalf("got %d trips and %d skipped, want 2 and 1", len(trips), skipped)
+	}
+	if trips[0].Days != 10 || trips[0].Destination != "France" {
+		t.Errorf("first trip = %+v, want 10 days to France", trips[0])
+	}
+	if trips[1].Days != 1 {
+		t.Errorf("record without an end lasts %d days, want 1", trips[1].Days)
+	}
+}
+
+func TestReadTripsFromAPIRefusesCrossOriginNext(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("followed next to another server, with Authorization %q", r.Header.Get("Authorization"))
+	}))
+	defer other.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"data": [{"start": "01.01.2025"}], "next": %q}`, other.URL+"/trips?page=2")
+	}))
+	defer server.Close()
+
+	_, _, err := readTripsFromAPI(server.URL+"/trips", Config{APIToken: "secret"})
+	if err == nil || !strings.Contains(err.Error(), other.URL) {
+		t.Errorf("error = %v, want one refusing the next page on %s", err, other.URL)
+	}
+
+	// Relative and same-origin URLs are still followed
+	for _, next := range []string{"/trips?page=2", server.URL + "/trips?page=2"} {
+		if got, err := nextPageURL(server.URL+"/trips", next); err != nil || got != server.URL+"/trips?page=2" {
+			t.Errorf("nextPageURL(%q) = %q, %v; want %s/trips?page=2", next, got, err, server.URL)
+		}
+	}
+}
+
+func TestReadTripsFromAPIInvalidEndDate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"data": [{"start": "01.09.2024", "end": "32.09.2024"}, {"start": "01.10.2024"}], "next": null}`)
+	}))
+	defer server.Close()
+
+	trips, skipped, err := readTripsFromAPI(server.URL, Config{})
+	if err != nil {
+		t.Fatalf("readTripsFromAPI: %v", err)
+	}
+	if len(trips) != 1 || skipped != 1 || trips[0].Days != 1 {
+		t.Errorf("got %+v and %d skipped, want the one-day record and 1 skipped", trips, skipped)
+	}
+
+	if _, _, err := readTripsFromAPI(server.URL, Config{Strict: true}); err == nil || !strings.Contains(err.Error(), "32.09.2024") {
+		t.Errorf("strict mode error = %v, want one naming the invalid end", err)
+	}
+}
+
+func TestReadTripsFromCSVISOWeeks(t *testing.T) {
+	path := writeTempCSV(t, "weeks.csv", "2024-W10\n2024-W52,2025-W01\n2020-W53,10.01.2021\n")
+
+	trips, skipped, err := readTripsFromCSV(path, Config{})
+	if err != nil {
+		t.Fatalf("readTripsFromCSV: %v", err)
+	}
+	if len(trips) != 3 || skipped != 0 {
+		t.Fatalf("got %d trips and %d skipped, want 3 and 0", len(trips), skipped)
+	}
+
+	want := []struct {
+		start, end string
+		days       int
+	}{
+		{"04.03.2024", "10.03.2024", 7},
+		{"23.12.2024", "05.01.2025", 14},
+		{"28.12.2020", "10.01.2021", 14},
+	}
+	for i, w := range want {
+		trip := trips[i]
+		if trip.Start.Format("02.01.2006") != w.start || trip.End.Format("02.01.2006") != w.end || trip.Days != w.days {
+			t.Errorf("trip %d = %s to %s (%d days), want %s to %s (%d days)", i,
+				trip.Start.Format("02.01.2006"), trip.End.Format("02.01.2006"), trip.Days, w.start, w.end, w.days)
+		}
+		if !trip.ByWeek {
+			t.Errorf("trip %d not marked as an ISO week", i)
+		}
+	}
+}
+
+func TestReadTripsFromCSVReversedRow(t *testing.T) {
+	path := writeTempCSV(t, "reversed.csv", "Start,End\n01.01.2025,10.01.2025\n20.02.2025,05.02.2025\n01.03.2025,03.03.2025\n")
+
+	trips, skipped, err := readTripsFromCSV(path, Config{})
+	if err != nil {
+		t.Fatalf("readTripsFromCSV: %v", err)
+	}
+	if len(trips) != 2 || skipped != 1 {
+		t.Fatalf("got %d trips and %d skipped, want 2 and 1", len(trips), skipped)
+	}
+	for _, trip := range trips {
+		if trip.Days <= 0 {
+			t.Errorf("trip %s has %d days", trip.Start.Format("02.01.2006"), trip.Days)
+		}
+	}
+
+	_, _, err = readTripsFromCSV(path, Config{Strict: true})
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("strict mode error = %v, want one naming line 3", err)
+	}
+}
+
+func TestReadTripsFromCSVAutoFixSwapped(t *testing.T) {
+	path := writeTempCSV(t, "reversed.csv", "Start,End\n01.01.2025,10.01.2025\n20.02.2025,05.02.2025\n2025-W12,2025-W10\n")
+
+	// Takes precedence over --strict
+	trips, skipped, err := readTripsFromCSV(path, Config{AutoFixSwapped: true, Strict: true})
+	if err != nil {
+		t.Fatalf("readTripsFromCSV: %v", err)
+	}
+	if len(trips) != 3 || skipped != 0 {
+		t.Fatalf("got %d trips and %d skipped, want 3 and 0", len(trips), skipped)
+	}
+	if got := trips[1].Start.Format("02.01.2006") + "-" + trips[1].End.Format("02.01.2006"); got != "05.02.2025-20.02.2025" || trips[1].Days != 16 {
+		t.Errorf("swapped trip = %s, %d days; want 05.02.2025-20.02.2025, 16 days", got, trips[1].Days)
+	}
+	// Swapped weeks run from the Monday of week 10 to the Sunday of week 12
+	if got := trips[2].Start.Format("02.01.2006") + "-" + trips[2].End.Format("02.01.2006"); got != "03.03.2025-23.03.2025" {
+		t.Errorf("swapped weeks = %s, want 03.03.2025-23.03.2025", got)
+	}
+}
+
+// captureStdout returns what f prints to stdout
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("os.Pipe: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() { os.Stdout = stdout }()
+
+	printed := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(reader)
+		printed <- string(data)
+	}()
+	f()
+	writer.Close()
+	return <-printed
+}
+
+func TestTripAnalysisFitsDestinationToWidth(t *testing.T) {
+	long := "The United Kingdom of Great Britain and Northern Ireland via Paris"
+	path := writeTempCSV(t, "trips.csv", "Start,End,Destination\n01.03.2026,10.03.2026,"+long+"\n01.04.2026,02.04.2026,France\n")
+	config := parseArgs([]string{"analyze", path, "--width", "60", "--date", "01.05.2026", "--no-color"})
+	trips, _, err := readTripsFromCSV(path, config)
+	if err != nil {
+		t.Fatalf("readTripsFromCSV: %v", err)
+	}
+
+	out := captureStdout(t, func() { displayTripAnalysis(trips, config) })
+	want := strings.Repeat(" ", 14) + "The United Kingdom of Great Britain and Nor..."
+	if !strings.Contains(out, want+"\n") || len(want) != 60 {
+		t.Errorf("output has no line %q cut to 60 columns:\n%s", want, out)
+	}
+	if strings.Contains(out, long) {
+		t.Errorf("output has the destination in full at --width 60:\n%s", out)
+	}
+
+	// At the default width every destination, short or long, has its own line
+	t.Setenv("COLUMNS", "")
+	config = parseArgs([]string{"analyze", path, "--date", "01.05.2026", "--no-color"})
+	if config.Width != 90 {
+		t.Fatalf("default width = %d, want 90", config.Width)
+	}
+	out = captureStdout(t, func() { displayTripAnalysis(trips, config) })
+	for _, want := range []string{"6.7%\n              France\n", "5.6%\n              " + long + "\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("at the default width, output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestReadTripsFromCSVRounding(t *testing.T) {
+	path := writeTempCSV(t, "rounding.csv", "Start,End\n"+
+		"2024-03-01T18:00,2024-03-05T09:00\n"+
+		"2024-04-01 00:00,2024-04-03 12:00\n"+
+		"10.05.2024,12.05.2024\n")
+
+	for _, tc := range []struct {
+		rounding absence.Rounding
+		want     []int
+	}{
+		{absence.Floor, []int{5, 3, 3}},
+		{absence.Ceil, []int{5, 4, 3}},
+		{absence.Round, []int{4, 4, 3}},
+		{"", []int{5, 3, 3}},
+	} {
+		trips, _, err := readTripsFromCSV(path, Config{Rounding: tc.rounding})
+		if err != nil {
+			t.Fatalf("%s: readTripsFromCSV: %v", tc.rounding, err)
+		}
+		var got []int
+		for _, trip := range trips {
+			got = append(got, trip.Days)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("--rounding %q: days = %v, want %v", tc.rounding, got, tc.want)
+		}
+	}
+}
+
+func TestReadTripsFromCSVStrictInvalidDate(t *testing.T) {
+	path := writeTempCSV(t, "swapped.csv", "Start,End\n01.01.2025,10.01.2025\nFrance,01.02.2025\n")
+
+	if _, skipped, err := readTripsFromCSV(path, Config{}); err != nil || skipped != 1 {
+		t.Errorf("lenient mode: skipped %d, err %v; want 1 skipped and no error", skipped, err)
+	}
+
+	_, _, err := readTripsFromCSV(path, Config{Strict: true})
+	if err == nil || !strings.Contains(err.Error(), "line 3") || !strings.Contains(err.Error(), "France") {
+		t.Errorf("strict mode error = %v, want one naming line 3 and its values", err)
+	}
+}
+
+func TestReadTripsFromCSVMixedDatesAndDateTimes(t *testing.T) {
+	path := writeTempCSV(t, "timestamps.csv", "Start,End\n"+
+		"01.01.2025,10.01.2025\n"+
+		"2025-02-01T08:15:00Z,2025-02-03T22:45:00+01:00\n"+
+		"2025-03-01 06:00:00,2025-03-01 23:59:59\n"+
+		"2025-04-01T23:30:00-05:00,05.04.2025\n")
+
+	trips, skipped, err := readTripsFromCSV(path, Config{})
+	if err != nil {
+		t.Fatalf("readTripsFromCSV: %v", err)
+	}
+	if len(trips) != 4 || skipped != 0 {
+		t.Fatalf("got %d trips and %d skipped, want 4 and 0", len(trips), skipped)
+	}
+
+	want := []struct {
+		start string
+		days  int
+	}{
+		{"01.01.2025", 10},
+		{"01.02.2025", 3},
+		{"01.03.2025", 1}, // same-day departure and return
+		{"01.04.2025", 5}, // date as written, not converted to UTC
+	}
+	for i, w := range want {
+		if got := trips[i].Start.Format("02.01.2006"); got != w.start || trips[i].Days != w.days {
+			t.Errorf("trip %d starts %s with %d days, want %s with %d", i, got, trips[i].Days, w.start, w.days)
+		}
+		if h, m, s := trips[i].Start.Clock(); h+m+s != 0 {
+			t.Errorf("trip %d start keeps a time of day", i)
+		}
+	}
+}
+
+func TestNextSafeTravelDate(t *testing.T) {
+	trips := []Trip{
+		{Start: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2025, 10, 30, 0, 0, 0, 0, time.UTC)},
+		{Start: time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC), End: time.Date(2026, 1, 20, 0, 0, 0, 0, time.UTC)},
+	}
+	config := Config{Config: absence.Config{WindowMonths: 12, AbsenceLimit: 180}}
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	// safe checks every window touching the planned trip the slow way
+	safe := func(start time.Time, length int) bool {
+		end := start.AddDate(0, 0, length-1)
+		withPlanned := append(append([]Trip{}, trips...), Trip{Start: start, End: end})
+		for day := start; !day.After(absence.WindowEnd(end, config.Config)); day = day.AddDate(0, 0, 1) {
+			if absence.CalculateDaysInWindow(withPlanned, absence.WindowStart(day, config.Config), day, config.DayCount) > config.AbsenceLimit {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, length := range []int{1, 30, 90, 180} {
+		start, ok := nextSafeTravelDate(trips, from, length, config)
+		if !ok {
+			t.Fatalf("%d-day trip: never safe, want a start date", length)
+		}
+		if !safe(start, length) {
+			t.Errorf("%d-day trip starting %s breaches the limit", length, start.Format("02.01.2006"))
+		}
+		if day := start.AddDate(0, 0, -1); !day.Before(from) && safe(day, length) {
+			t.Errorf("%d-day trip: got %s, but %s is already safe", length, start.Format("02.01.2006"), day.Format("02.01.2006"))
+		}
+	}
+
+	if _, ok := nextSafeTravelDate(trips, from, 181, config); ok {
+		t.Error("181-day trip: got a start date, want never safe")
+	}
+}
+
+func TestMaxContinuousStayFutureTrip(t *testing.T) {
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	trips := []Trip{
+		{Start: from.AddDate(0, 0, 10), End: from.AddDate(0, 0, 34)},
+	}
+	config := Config{Config: absence.Config{WindowMonths: 12, AbsenceLimit: 30}}
+
+	// The 25 recorded days after the trip share a window with it, so only
+	// 5 days are left even though the window ending on the trip is empty
+	days, unlimited := maxContinuousStay(trips, from, config)
+	if days != 5 || unlimited {
+		t.Fatalf("maxContinuousStay = %d, %v, want 5, false", days, unlimited)
+	}
+
+	if start, ok := nextSafeTravelDate(trips, from, days, config); !ok || !start.Equal(from) {
+		t.Errorf("%d-day trip: next safe start %s, want %s", days, start.Format("02.01.2006"), from.Format("02.01.2006"))
+	}
+	if start, _ := nextSafeTravelDate(trips, from, days+1, config); start.Equal(from) {
+		t.Errorf("%d-day trip: next safe start %s, want a later date", days+1, start.Format("02.01.2006"))
+	}
+}
+
+func TestReadTripsFromCSVDelimiters(t *testing.T) {
+	semicolons := writeTempCSV(t, "semicolon.csv", "Start;End;Destination\n"+
+		"01.03.2024;10.03.2024;France\n"+
+		"15.04.2024;20.04.2024;Spain, Mallorca\n")
+	tabs := writeTempCSV(t, "tabs.tsv", "01.03.2024\t10.03.2024\n15.04.2024\t20.04.2024\n")
+
+	for _, tc := range []struct {
+		name      string
+		path      string
+		delimiter rune
+	}{
+		{"semicolon detected", semicolons, 0},
+		{"semicolon given", semicolons, ';'},
+		{"tab detected", tabs, 0},
+	} {
+		trips, skipped, err := readTripsFromCSV(tc.path, Config{Delimiter: tc.delimiter})
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if len(trips) != 2 || skipped != 0 {
+			t.Fatalf("%s: got %d trips and %d skipped, want 2 and 0", tc.name, len(trips), skipped)
+		}
+		if trips[1].Days != 6 {
+			t.Errorf("%s: second trip has %d days, want 6", tc.name, trips[1].Days)
+		}
+	}
+
+	trips, _, err := readTripsFromCSV(semicolons, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trips[1].Destination != "Spain, Mallorca" {
+		t.Errorf("destination = %q, want the comma kept inside the field", trips[1].Destination)
+	}
+
+	// Forcing a comma leaves each semicolon row as one unreadable field
+	if trips, _, _ := readTripsFromCSV(semicolons, Config{Delimiter: ','}); len(trips) != 0 {
+		t.Errorf("--delimiter , read %d trips from a semicolon file, want 0", len(trips))
+	}
+}
+
+func TestFilterByDateRange(t *testing.T) {
+	trips, _, err := readTripsFromCSV(filepath.Join("..", "tests", "fixtures", "date-range.csv"), Config{})
+	if err != nil {
+		t.Fatalf("readTripsFromCSV: %v", err)
+	}
+
+	config := Config{
+		From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC),
+	}
+	kept := filterByDateRange(trips, config)
+
+	// The 2022 trip is dropped; the trips over each new year are clipped
+	want := []struct {
+		start, end string
+		days       int
+	}{
+		{"01.01.2024", "10.01.2024", 10},
+		{"01.05.2024", "15.05.2024", 15},
+		{"25.12.2025", "31.12.2025", 7},
+	}
+	if len(kept) != len(want) {
+		t.Fatalf("kept %d trips, want %d", len(kept), len(want))
+	}
+	for i, w := range want {
+		start, end := kept[i].Start.Format("02.01.2006"), kept[i].End.Format("02.01.2006")
+		if start != w.start || end != w.end || kept[i].Days != w.days {
+			t.Errorf("trip %d = %s to %s (%d days), want %s to %s (%d days)",
+				i, start, end, kept[i].Days, w.start, w.end, w.days)
+		}
+	}
+}
+
+func TestFilterByResidenceStart(t *testing.T) {
+	trips := []Trip{
+		{Start: time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2021, 2, 20, 0, 0, 0, 0, time.UTC), Days: 20},
+		{Start: time.Date(2021, 3, 25, 0, 0, 0, 0, time.UTC), End: time.Date(2021, 4, 5, 0, 0, 0, 0, time.UTC), Days: 12},
+		{Start: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2021, 6, 10, 0, 0, 0, 0, time.UTC), Days: 10},
+	}
+	// An earlier --from gives way to the residence start
+	config := Config{
+		From:           time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		ResidenceStart: time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC),
+	}
+	kept := filterByDateRange(trips, config)
+
+	if len(kept) != 2 {
+		t.Fatalf("kept %d trips, want 2", len(kept))
+	}
+	if !kept[0].Start.Equal(config.ResidenceStart) || kept[0].Days != 5 || !kept[0].Clipped {
+		t.Errorf("straddling trip = %s, %d days, clipped %v; want from 01.04.2021, 5 days, clipped",
+			kept[0].Start.Format("02.01.2006"), kept[0].Days, kept[0].Clipped)
+	}
+	if kept[1].Clipped {
+		t.Error("trip after the residence start marked clipped")
+	}
+}
+
+func TestTargetDateBeforeTrips(t *testing.T) {
+	trips := []Trip{
+		{Start: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC), Days: 10},
+		{Start: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2024, 6, 5, 0, 0, 0, 0, time.UTC), Days: 5},
+	}
+	config := Config{
+		Config:     absence.Config{WindowMonths: 12, AbsenceLimit: 180},
+		CustomDate: "01.01.2023",
+		DateFormat: "02.01.2006",
+	}
+
+	if days, ok := daysSinceLastTrip(trips, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)); ok || days != 0 {
+		t.Errorf("daysSinceLastTrip = %d, %v; want 0, false before the last trip ends", days, ok)
+	}
+
+	output, err := buildJSONOutput(trips, config)
+	if err != nil {
+		t.Fatalf("buildJSONOutput: %v", err)
+	}
+	if output.Status.DaysSinceLastTrip != 0 || !output.Status.DaysSinceLastTripNotApplicable || output.Status.TotalDaysOutside != 0 {
+		t.Errorf("status = %d days since last trip (not applicable %v), %d outside; want 0 marked not applicable, and 0",
+			output.Status.DaysSinceLastTrip, output.Status.DaysSinceLastTripNotApplicable, output.Status.TotalDaysOutside)
+	}
+	if len(output.Warnings) != 1 || !strings.Contains(output.Warnings[0], "before the first trip") {
+		t.Errorf("warnings = %q, want one about the date preceding all trips", output.Warnings)
+	}
+}
+
+func TestReadTripsFromFilesJSON(t *testing.T) {
+	array := writeTempCSV(t, "trips.json", `[
+		{"start": "01.03.2024", "end": "10.03.2024", "destination": "France"},
+		{"start": "2024-04-15", "end": "2024-04-20"},
+		{"start": "not a date", "end": "2024-05-01"}
+	]`)
+	page := writeTempCSV(t, "export.txt", `{"trips": [{"start": "01.06.2024", "end": "05.06.2024"}]}`)
+
+	trips, skipped, err := readTripsFromFiles([]string{array}, Config{})
+	if err != nil {
+		t.Fatalf("readTripsFromFiles: %v", err)
+	}
+	if len(trips) != 2 || skipped != 1 {
+		t.Fatalf("got %d trips and %d skipped, want 2 and 1", len(trips), skipped)
+	}
+	if trips[0].Destination != "France" || trips[1].Days != 6 {
+		t.Errorf("trips = %+v, want France first and a 6-day second trip", trips)
+	}
+
+	// Without a .json extension the format has to be given
+	trips, _, err = readTripsFromFiles([]string{page}, Config{InputFormat: "json"})
+	if err != nil || len(trips) != 1 {
+		t.Errorf("--input-format json read %d trips, err %v; want 1", len(trips), err)
+	}
+}
+
+func TestReadTripsAddsProjectedTrips(t *testing.T) {
+	path := writeTempCSV(t, "trips.csv", "Start,End,Destination\n01.03.2026,10.03.2026,France\n")
+	projected := Trip{Start: time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2026, 6, 15, 0, 0, 0, 0, time.UTC), Projected: true}
+	config := Config{Filenames: []string{path}, ProjectedTrips: []Trip{projected}, CountMode: "countries", Countries: []string{"Spain"}}
+
+	trips, _, err := readTrips(config)
+	if err != nil {
+		t.Fatalf("readTrips: %v", err)
+	}
+	if len(trips) != 2 || !trips[1].Projected || trips[1].Days != 15 {
+		t.Fatalf("trips = %+v, want the file's trip and a 15-day projected trip", trips)
+	}
+
+	// A projected trip has no destination but still counts in countries mode
+	if kept := filterByCountries(trips, config); len(kept) != 1 || !kept[0].Projected {
+		t.Errorf("countries mode kept %+v, want only the projected trip", kept)
+	}
+}
+
+func TestReturnDayCounts(t *testing.T) {
+	path := writeTempCSV(t, "trips.csv", "01.03.2026,10.03.2026\n01.04.2026,01.04.2026\n")
+	windowStart, windowEnd := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		flag   string
+		days   []int
+		window int
+	}{
+		{"--return-day-counts=true", []int{10, 1}, 11},
+		{"--return-day-counts=false", []int{9, 0}, 9},
+	} {
+		config := parseArgs([]string{"analyze", path, tc.flag})
+		trips, _, err := readTrips(config)
+		if err != nil {
+			t.Fatalf("%s: readTrips: %v", tc.flag, err)
+		}
+
+		// One day fewer per trip, in the trip's days and in the window
+		for i, want := range tc.days {
+			if trips[i].Days != want {
+				t.Errorf("%s: trip %d has %d days, want %d", tc.flag, i, trips[i].Days, want)
+			}
+		}
+		if got := absence.CalculateDaysInWindow(trips, windowStart, windowEnd, config.DayCount); got != tc.window {
+			t.Errorf("%s: window has %d days, want %d", tc.flag, got, tc.window)
+		}
+	}
+}
+
+func TestSameDayCounts(t *testing.T) {
+	// A day trip on its own, and one on the return day of a longer trip
+	path := writeTempCSV(t, "trips.csv", "01.03.2026,01.03.2026\n10.03.2026,12.03.2026\n12.03.2026,12.03.2026\n")
+	windowStart, windowEnd := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		flag     string
+		excluded int
+		window   int
+	}{
+		{"--same-day-counts=true", 0, 4},
+		{"--same-day-counts=false", 2, 3},
+	} {
+		config := parseArgs([]string{"analyze", path, tc.flag})
+		trips, _, err := readTrips(config)
+		if err != nil {
+			t.Fatalf("%s: readTrips: %v", tc.flag, err)
+		}
+		trips = excludeTrips(trips, config)
+
+		// Same-day trips stay listed, and the longer trip still counts 12 March
+		excluded := 0
+		for _, trip := range trips {
+			if trip.Excluded != "" {
+				excluded++
+			}
+		}
+		if len(trips) != 3 || excluded != tc.excluded {
+			t.Errorf("%s: %d trips with %d excluded, want 3 with %d", tc.flag, len(trips), excluded, tc.excluded)
+		}
+		if got := absence.CalculateDaysInWindow(trips, windowStart, windowEnd, config.DayCount); got != tc.window {
+			t.Errorf("%s: window has %d days, want %d", tc.flag, got, tc.window)
+		}
+	}
+}
+
+func TestReadTripsFromCSVOngoing(t *testing.T) {
+	path := writeTempCSV(t, "trips.csv", "Start,End\n01.03.2026,10.03.2026\n01.10.2026,ongoing\n05.10.2026,\n")
+	config := Config{CustomDate: "15.10.2026"}
+
+	trips, _, err := readTripsFromCSV(path, config)
+	if err != nil {
+		t.Fatalf("readTripsFromCSV: %v", err)
+	}
+	if len(trips) != 3 || trips[0].Ongoing {
+		t.Fatalf("trips = %+v, want a finished trip and two ongoing ones", trips)
+	}
+
+	// Both an "ongoing" and an empty end run to the target date
+	target := time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)
+	for _, trip := range trips[1:] {
+		if !trip.Ongoing || !trip.End.Equal(target) {
+			t.Errorf("trip %+v, want ongoing to 15.10.2026", trip)
+		}
+	}
+	if trips[1].Days != 15 {
+		t.Errorf("ongoing trip counts %d days, want 15", trips[1].Days)
+	}
+}
+
+func TestReadTripsInteractive(t *testing.T) {
+	// An invalid date and a reversed trip are asked for again
+	input := "01.03.2024\nfoo\n10.03.2024\nbad\n05.05.2024\n01.05.2024\n\n"
+	var prompts strings.Builder
+
+	trips, _, err := readTripsInteractive(strings.NewReader(input), &prompts, Config{})
+	if err != nil {
+		t.Fatalf("readTripsInteractive: %v", err)
+	}
+	if len(trips) != 2 || trips[0].Days != 10 || trips[1].Days != 1 {
+		t.Fatalf("trips = %+v, want a 10-day trip and a one-day trip", trips)
+	}
+	for _, want := range []string{"Invalid date 'foo'", "Invalid date 'bad'", "can't end before it starts"} {
+		if !strings.Contains(prompts.String(), want) {
+			t.Errorf("prompts missing %q:\n%s", want, prompts.String())
+		}
+	}
+}
+
+func TestConfigFile(t *testing.T) {
+	path := writeTempCSV(t, "trips.csv", "01.03.2026,10.03.2026\n")
+	t.Chdir(filepath.Dir(path))
+	if err := os.WriteFile(".stay-within.yaml", []byte("# ILR tracking\nwindow: 60\nlimit: 450 # five years\ndate-format: iso\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// File values replace the built-in defaults
+	config := parseArgs([]string{"analyze", path})
+	if config.WindowMonths != 60 || config.AbsenceLimit != 450 || config.DateFormat != "2006-01-02" {
+		t.Errorf("window %d, limit %d, date format %q; want 60, 450 and ISO from the config file",
+			config.WindowMonths, config.AbsenceLimit, config.DateFormat)
+	}
+
+	// Flags on the command line win over the file
+	config = parseArgs([]string{"analyze", path, "--limit", "300"})
+	if config.WindowMonths != 60 || config.AbsenceLimit != 300 {
+		t.Errorf("window %d, limit %d; want 60 from the file and 300 from --limit", config.WindowMonths, config.AbsenceLimit)
+	}
+
+	// So does a preset, over the file's window and limit
+	config = parseArgs([]string{"analyze", path, "--preset", "schengen"})
+	if config.WindowDays != 180 || config.AbsenceLimit != 90 {
+		t.Errorf("window %d days, limit %d; want the schengen preset's 180 and 90", config.WindowDays, config.AbsenceLimit)
+	}
+}
+
+func TestCountryFilters(t *testing.T) {
+	path := writeTempCSV(t, "trips.csv", "Start,End,Destination\n01.03.2026,10.03.2026,France\n01.04.2026,05.04.2026,Ireland\n01.05.2026,03.05.2026,Spain\n")
+
+	for _, tc := range []struct {
+		flag, list string
+		want       []string
+	}{
+		{"--only-countries", "france, spain", []string{"France", "Spain"}},
+		{"--exclude-countries", "Ireland", []string{"France", "Spain"}},
+		{"--exclude-countries", "France,Spain", []string{"Ireland"}},
+	} {
+		config := parseArgs([]string{"analyze", path, tc.flag, tc.list})
+		trips, _, err := readTrips(config)
+		if err != nil {
+			t.Fatalf("readTrips: %v", err)
+		}
+		kept := filterByCountries(trips, config)
+
+		var got []string
+		for _, trip := range kept {
+			got = append(got, trip.Destination)
+		}
+		if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("%s %s kept %v, want %v", tc.flag, tc.list, got, tc.want)
+		}
+
+		// The data summary still counts the left-out trips as parsed
+		config.FilteredTrips = len(trips) - len(kept)
+		summary := summarizeData(kept, time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC), config)
+		if summary.Parsed != 3 || summary.Filtered != 3-len(tc.want) {
+			t.Errorf("%s %s: summary %q", tc.flag, tc.list, summary)
+		}
+	}
+}
+
+func TestReadTripsFromCSVGzip(t *testing.T) {
+	plain, _, err := readTripsFromCSV(filepath.Join("..", "tests", "fixtures", "basic.csv"), Config{})
+	if err != nil {
+		t.Fatalf("readTripsFromCSV: %v", err)
+	}
+	compressed, _, err := readTripsFromCSV(filepath.Join("..", "tests", "fixtures", "basic.csv.gz"), Config{})
+	if err != nil {
+		t.Fatalf("readTripsFromCSV (gzip): %v", err)
+	}
+
+	if len(compressed) == 0 || fmt.Sprint(compressed) != fmt.Sprint(plain) {
+		t.Errorf("basic.csv.gz read %v, want the same trips as basic.csv: %v", compressed, plain)
+	}
+}
+
+func TestWriteYAML(t *testing.T) {
+	output := jsonOutput{
+		Trips:    []jsonTrip{{Start: "01.03.2024", End: "05.03.2024", Days: 5, Destination: "Côte d'Ivoire"}},
+		Status:   jsonStatus{Status: "ok"},
+		Warnings: []string{"no"},
+	}
+	output.Config.AbsenceLimit = 180
+
+	var out strings.Builder
+	if err := writeYAML(&out, output); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"config:\n  windowMonths: 0\n  absenceLimit: 180\n",
+		"trips:\n  - start: \"01.03.2024\"\n    end: \"05.03.2024\"\n    days: 5\n",
+		"    destination: \"Côte d'Ivoire\"\n",
+		"  status: ok\n",
+		"warnings:\n  - \"no\"\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("YAML missing %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "application") {
+		t.Errorf("omitempty field written:\n%s", out.String())
+	}
+}
+
+func TestTimeZone(t *testing.T) {
+	location, err := time.LoadLocation("Pacific/Kiritimati")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := Config{Midnight: true, Location: location}
+
+	targetDate, err := parseTargetDate(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Now().In(location).Format("2006-01-02"); targetDate.Format("2006-01-02") != want {
+		t.Errorf("today = %s, want %s", targetDate.Format("2006-01-02"), want)
+	}
+
+	if got := localizeDate("2024-03-01T23:30:00-05:00", config); got != "2024-03-02" {
+		t.Errorf("localizeDate = %q, want 2024-03-02", got)
+	}
+	if got := localizeDate("2024-03-01T23:30:00", config); got != "2024-03-01T23:30:00" {
+		t.Errorf("localizeDate changed a timestamp without offset: %q", got)
+	}
+}
+
+func TestNextRelease(t *testing.T) {
+	config := Config{Config: absence.Config{WindowMonths: 12, AbsenceLimit: 180}}
+	trips := []Trip{
+		{Start: time.Date(2023, 5, 25, 0, 0, 0, 0, time.UTC), End: time.Date(2023, 8, 10, 0, 0, 0, 0, time.UTC)},
+		{Start: time.Date(2023, 9, 15, 0, 0, 0, 0, time.UTC), End: time.Date(2023, 9, 20, 0, 0, 0, 0, time.UTC)},
+	}
+
+	releaseDate, days, ok := nextRelease(trips, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), config)
+	if !ok || days != 71 || !releaseDate.Equal(time.Date(2024, 8, 11, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("nextRelease = %s, %d, %v; want 11.08.2024, 71, true", releaseDate.Format("02.01.2006"), days, ok)
+	}
+
+	if _, _, ok := nextRelease(trips, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), config); ok {
+		t.Error("nextRelease reported a release for an empty window")
+	}
+}
+
+func TestPinnedNow(t *testing.T) {
+	config := Config{
+		Config:     absence.Config{WindowMonths: 12, AbsenceLimit: 180},
+		DateFormat: "02.01.2006",
+		Midnight:   true,
+		Now:        time.Date(2024, 6, 1, 15, 30, 0, 0, time.UTC),
+		Filename:   "trips.csv",
+	}
+	trips := []Trip{{Start: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC), Days: 10}}
+
+	output, err := buildJSONOutput(trips, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Status.TargetDate != "01.06.2024" || output.Status.DaysSinceLastTrip != 22 || output.Status.DaysSinceLastTripNotApplicable || output.Status.DaysRemaining != 170 {
+		t.Errorf("status = %+v, want 01.06.2024 with 22 days since the last trip and 170 remaining", output.Status)
+	}
+	if output.SchemaVersion != jsonSchemaVersion || output.Meta.Input != "trips.csv" || output.Meta.Version != version {
+		t.Errorf("schemaVersion %d, meta %+v; want %d for trips.csv", output.SchemaVersion, output.Meta, jsonSchemaVersion)
+	}
+}
+
+func TestRules(t *testing.T) {
+	for _, tc := range []struct {
+		value string
+		want  Rule
+	}{
+		{"12mo:180", Rule{"12mo:180", 12, 0, 180, 0}},
+		{"5y:450:45", Rule{"5y:450:45", 60, 0, 450, 45}},
+		{"180d:90", Rule{"180d:90", 0, 180, 90, 0}},
+		{"schengen", Rule{"schengen", 0, 180, 90, 0}},
+		{"ilr-5yr:60", Rule{"ilr-5yr:60", 60, 0, 450, 60}},
+	} {
+		if got, err := parseRule(tc.value); err != nil || got != tc.want {
+			t.Errorf("parseRule(%q) = %+v, %v; want %+v", tc.value, got, err, tc.want)
+		}
+	}
+	for _, value := range []string{"12mo", "12x:180", "0mo:180", "12mo:-1", "12mo:180:0", "12mo:180:5:5", "schengen:x"} {
+		if _, err := parseRule(value); err == nil {
+			t.Errorf("parseRule(%q) succeeded, want an error", value)
+		}
+	}
+
+	config := Config{Rules: []Rule{{"12mo:180", 12, 0, 180, 0}, {"180d:30", 0, 180, 30, 0}}}
+	trips := []Trip{{Start: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2024, 4, 9, 0, 0, 0, 0, time.UTC)}}
+	results := evaluateRules(trips, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), config)
+	if len(results) != 2 || !results[0].Pass || results[1].Pass || results[1].DaysRemaining != -10 {
+		t.Errorf("evaluateRules = %+v, want the 12-month rule to pass and the 180-day rule to fail by 10", results)
+	}
+
+	// Each rule's own warn-at decides its caution, others use the default
+	config.Rules = []Rule{{"12mo:180:150", 12, 0, 180, 150}, {"60mo:450", 60, 0, 450, 0}}
+	results = evaluateRules(trips, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), config)
+	if results[0].Status != "caution" || results[1].Status != "ok" {
+		t.Errorf("statuses %s and %s, want caution and ok", results[0].Status, results[1].Status)
+	}
+}
+
+func TestFindOutOfOrder(t *testing.T) {
+	trips := []Trip{
+		{Start: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)},
+		{Start: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{Start: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2024, 6, 5, 0, 0, 0, 0, time.UTC)},
+	}
+	if got := findOutOfOrder(trips); len(got) != 1 || got[0] != trips[1] {
+		t.Errorf("findOutOfOrder = %+v, want only the January trip", got)
+	}
+}
+
+func TestReadTripsFromXLSX(t *testing.T) {
+	trips, skipped, err := readTripsFromXLSX(filepath.Join("..", "tests", "fixtures", "basic.xlsx"), Config{})
+	if err != nil {
+		t.Fatalf("readTripsFromXLSX: %v", err)
+	}
+	if len(trips) != 3 || skipped != 0 {
+		t.Fatalf("got %d trips and %d skipped, want 3 and 0", len(trips), skipped)
+	}
+	if want := time.Date(2023, 5, 25, 0, 0, 0, 0, time.UTC); !trips[0].Start.Equal(want) || trips[0].Days != 78 || trips[0].Destination != "France" {
+		t.Errorf("first trip = %+v, want 25.05.2023 to 10.08.2023 in France", trips[0])
+	}
+
+	// A workbook written by --xlsx-out reads back as the same trips
+	path := filepath.Join(t.TempDir(), "analysis.xlsx")
+	config := Config{Config: absence.Config{WindowMonths: 12, AbsenceLimit: 180}}
+	if err := writeXLSX(path, trips, config); err != nil {
+		t.Fatal(err)
+	}
+	again, _, err := readTripsFromXLSX(path, Config{})
+	if err != nil {
+		t.Fatalf("readTripsFromXLSX: %v", err)
+	}
+	if len(again) != 3 || !again[2].End.Equal(trips[2].End) {
+		t.Errorf("read back %+v, want %+v", again, trips)
+	}
+
+	// It has the status column of --csv-out
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := readXLSXRows(data)
+	if err != nil {
+		t.Fatalf("readXLSXRows: %v", err)
+	}
+	if len(rows[0]) != 6 || rows[0][5] != "Status" || rows[1][5] != "ok" {
+		t.Errorf("header %q and first row %q, want a Status column with ok", rows[0], rows[1])
+	}
+}
+
+func TestYearTotals(t *testing.T) {
+	trips := []Trip{
+		{Start: time.Date(2023, 5, 25, 0, 0, 0, 0, time.UTC), End: time.Date(2023, 8, 10, 0, 0, 0, 0, time.UTC)},
+		{Start: time.Date(2023, 12, 24, 0, 0, 0, 0, time.UTC), End: time.Date(2025, 1, 4, 0, 0, 0, 0, time.UTC)},
+	}
+	want := []yearTotal{{2023, 86}, {2024, 366}, {2025, 4}}
+	if got := yearTotals(trips, Config{}); !slices.Equal(got, want) {
+		t.Errorf("yearTotals = %v, want %v", got, want)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	config := Config{Config: absence.Config{WindowMonths: 12, AbsenceLimit: 180}, Rules: []Rule{{"180d:30", 0, 180, 30, 0}}}
+	planned := Trip{Start: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2024, 7, 30, 0, 0, 0, 0, time.UTC), Days: 30, Projected: true}
+	config.ProjectedTrips = []Trip{planned}
+	trips := []Trip{
+		{Start: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC), Days: 10},
+		planned,
+	}
+
+	date, before, after := dryRun(trips, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), config)
+	if !date.Equal(planned.End) {
+		t.Errorf("date = %s, want the end of the planned trip", date.Format("02.01.2006"))
+	}
+	if len(before) != 2 || before[0].Rule.Name != "12mo:180" || before[0].TotalDaysOutside != 10 || after[0].TotalDaysOutside != 40 {
+		t.Errorf("main rule before %+v, after %+v; want 10 then 40 days", before[0], after[0])
+	}
+	if before[1].Status != "ok" || after[1].Status != "exceeded" {
+		t.Errorf("180d:30 status %s -> %s, want ok -> exceeded", before[1].Status, after[1].Status)
+	}
+}
+
+func TestStayPutProjection(t *testing.T) {
+	config := Config{Config: absence.Config{WindowMonths: 12, AbsenceLimit: 180}}
+	trips := []Trip{{Start: time.Date(2023, 6, 21, 0, 0, 0, 0, time.UTC), End: time.Date(2023, 8, 10, 0, 0, 0, 0, time.UTC)}}
+
+	var remaining []int
+	for _, point := range stayPutProjection(trips, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), config) {
+		remaining = append(remaining, point.DaysRemaining)
+	}
+	// The window ending 01.07.2024 starts on 01.07.2023, leaving 41 days of the trip
+	if want := []int{139, 180, 180}; !slices.Equal(remaining, want) {
+		t.Errorf("days remaining = %v, want %v", remaining, want)
+	}
+}
+
+func TestForwardStanding(t *testing.T) {
+	config := Config{Config: absence.Config{WindowMonths: 12, AbsenceLimit: 180}}
+	trips := []Trip{
+		{Start: time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2024, 11, 30, 0, 0, 0, 0, time.UTC)},
+		{Start: time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC), End: time.Date(2025, 3, 24, 0, 0, 0, 0, time.UTC)},
+		{Start: time.Date(2025, 12, 28, 0, 0, 0, 0, time.UTC), End: time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)},
+	}
+
+	// Only trips on or after the target date count, the last one up to
+	// 01.01.2026
+	forward := forwardStanding(trips, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), config)
+	if !forward.End.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("forward window ends %s, want 01.01.2026", forward.End.Format("02.01.2006"))
+	}
+	if forward.TotalDaysOutside != 15+5 || forward.DaysRemaining != 160 || len(forward.Contributions) != 2 {
+		t.Errorf("forward window = %d days outside, %d remaining, %d trips; want 20, 160, 2",
+			forward.TotalDaysOutside, forward.DaysRemaining, len(forward.Contributions))
+	}
+}
+
+func TestLongTrips(t *testing.T) {
+	trips := []Trip{
+		{Start: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2024, 3, 30, 0, 0, 0, 0, time.UTC), Days: 90},
+		{Start: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2024, 8, 30, 0, 0, 0, 0, time.UTC), Days: 91},
+	}
+	config := Config{Config: absence.Config{WindowMonths: 12, AbsenceLimit: 180}}
+
+	if long := longTrips(trips, config); long != nil {
+		t.Errorf("without --max-single, long trips = %v, want none", long)
+	}
+	config.MaxSingle = 90
+	if long := longTrips(trips, config); len(long) != 1 || long[0] != trips[1] {
+		t.Errorf("--max-single 90: long trips = %v, want only the 91-day trip", long)
+	}
+
+	output, err := buildJSONOutput(trips, config)
+	if err != nil {
+		t.Fatalf("buildJSONOutput: %v", err)
+	}
+	if len(output.LongTrips) != 1 || output.LongTrips[0].OverBy != 1 {
+		t.Errorf("longTrips = %+v, want the 91-day trip over by 1", output.LongTrips)
+	}
+}
+
+func TestPendingReturn(t *testing.T) {
+	trips := []Trip{
+		{Start: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), End: time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC), Days: 16},
+		{Start: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2024, 6, 14, 0, 0, 0, 0, time.UTC), Days: 14},
+	}
+	config := Config{Config: absence.Config{WindowMonths: 12, AbsenceLimit: 180}, DateFormat: "02.01.2006"}
+
+	// Inside the final trip: abroad until its end, not a negative days since
+	config.CustomDate = "10.06.2024"
+	output, err := buildJSONOutput(trips, config)
+	if err != nil {
+		t.Fatalf("buildJSONOutput: %v", err)
+	}
+	if !output.Status.DaysSinceLastTripNotApplicable || output.Status.AbroadUntil != "14.06.2024" || output.Status.DaysUntilReturn != 4 {
+		t.Errorf("on 10.06.2024: days since last trip applicable %v, abroad until %q, back in %d; want not applicable, 14.06.2024, 4",
+			!output.Status.DaysSinceLastTripNotApplicable, output.Status.AbroadUntil, output.Status.DaysUntilReturn)
+	}
+
+	// Before the final trip: the next trip instead
+	config.CustomDate = "01.03.2024"
+	output, err = buildJSONOutput(trips, config)
+	if err != nil {
+		t.Fatalf("buildJSONOutput: %v", err)
+	}
+	if output.Status.AbroadUntil != "" || output.Status.NextTripStart != "01.06.2024" {
+		t.Errorf("on 01.03.2024: abroad until %q, next trip %q; want none and 01.06.2024",
+			output.Status.AbroadUntil, output.Status.NextTripStart)
+	}
+
+	if _, _, ok := pendingReturn(trips, time.Date(2024, 6, 14, 0, 0, 0, 0, time.UTC)); ok {
+		t.Error("on the final trip's return day, a return is still pending")
+	}
+}
+
+func TestSortRows(t *testing.T) {
+	trips := []Trip{
+		{Start: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2024, 3, 30, 0, 0, 0, 0, time.UTC), Days: 90},
+		{Start: time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2024, 4, 10, 0, 0, 0, 0, time.UTC), Days: 132},
+		{Start: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC), Days: 10},
+	}
+	config := absence.Config{WindowMonths: 12, AbsenceLimit: 180}
+	result := absence.Analyze(trips, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), config)
+
+	for _, tc := range []struct {
+		by   string
+		want []int
+	}{
+		{"end", []int{90, 132, 10}},
+		{"start", []int{132, 90, 10}},
+		{"days", []int{132, 90, 10}},
+		// The last trip's window also holds the other two
+		{"remaining", []int{10, 132, 90}},
+	} {
+		var days []int
+		for _, row := range sortRows(result.Trips, tc.by) {
+			days = append(days, row.Trip.Days)
+		}
+		if !slices.Equal(days, tc.want) {
+			t.Errorf("--sort %s: trips of %v days, want %v", tc.by, days, tc.want)
+		}
+	}
+	if result.Trips[0].Trip.Days != 90 {
+		t.Error("sortRows reordered the analysis rows in place")
+	}
+}
+
+func TestCalendarMonth(t *testing.T) {
+	trips := []Trip{{Start: time.Date(2024, 2, 27, 0, 0, 0, 0, time.UTC), End: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)}}
+	config := Config{FirstDayOfWeek: time.Sunday}
+
+	// 1 February 2024 is a Thursday; the leap day is abroad
+	lines := calendarMonth(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), abroadDays(trips, config), config)
+	want := []string{
+		"     February 2024 (3)      ",
+		"  Su  Mo  Tu  We  Th  Fr  Sa",
+		"                   1   2   3",
+		"   4   5   6   7   8   9  10",
+		"  11  12  13  14  15  16  17",
+		"  18  19  20  21  22  23  24",
+		"  25  26 *27 *28 *29        ",
+	}
+	if !slices.Equal(lines, want) {
+		t.Errorf("calendar =\n%s\nwant\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+
+	config.FirstDayOfWeek = time.Monday
+	if lines := calendarMonth(time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), abroadDays(trips, config), config); lines[2] != "   1   2   3   4   5   6   7" {
+		t.Errorf("April 2024 from Monday starts %q, want the 1st in the first column", lines[2])
+	}
+}
+
+func TestActiveRows(t *testing.T) {
+	trips := []Trip{
+		{Start: time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2022, 3, 10, 0, 0, 0, 0, time.UTC), Days: 10},
+		{Start: time.Date(2023, 5, 25, 0, 0, 0, 0, time.UTC), End: time.Date(2023, 6, 5, 0, 0, 0, 0, time.UTC), Days: 12},
+		{Start: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC), Days: 10},
+		{Start: time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2024, 8, 10, 0, 0, 0, 0, time.UTC), Days: 10},
+	}
+	result := absence.Analyze(trips, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), absence.Config{WindowMonths: 12, AbsenceLimit: 180})
+
+	// The window from 01.06.2023 catches the end of the second trip; the
+	// first ended long before and the last starts after the target date
+	active := activeRows(result.Trips, result.WindowStart, result.TargetDate)
+	if len(active) != 2 || active[0].Trip != trips[1] || active[1].Trip != trips[2] {
+		t.Errorf("active rows = %+v, want the second and third trips", active)
+	}
+}
+
+func TestPersonColumn(t *testing.T) {
+	path := writeTempCSV(t, "household.csv", "Name,Start,End\n"+
+		"Sam,15.09.2023,20.09.2023\n"+
+		"Alex,25.05.2023,10.08.2023\n"+
+		",01.10.2023,02.10.2023\n"+
+		"Alex,24.12.2023,04.01.2024\n")
+
+	config := Config{PersonColumn: "Name", StartColumn: "Start", EndColumn: "End"}
+	trips, skipped, err := readTripsFromCSV(path, config)
+	if err != nil {
+		t.Fatalf("readTripsFromCSV: %v", err)
+	}
+	if len(trips) != 4 || skipped != 0 {
+		t.Fatalf("got %d trips and %d skipped, want 4 and 0", len(trips), skipped)
+	}
+
+	groups := groupByPerson(trips)
+	var names []string
+	for _, group := range groups {
+		names = append(names, fmt.Sprintf("%s:%d", group.Name, len(group.Trips)))
+	}
+	if want := []string{"(unnamed):1", "Alex:2", "Sam:1"}; !slices.Equal(names, want) {
+		t.Errorf("groups = %v, want %v", names, want)
+	}
+
+	// A number works without a header row too
+	path = writeTempCSV(t, "household.csv", "25.05.2023,10.08.2023,Alex\n15.09.2023,20.09.2023,Sam\n")
+	trips, _, err = readTripsFromCSV(path, Config{PersonColumn: "3"})
+	if err != nil || len(trips) != 2 || trips[1].Person != "Sam" {
+		t.Errorf("with --person-col 3: %+v, %v; want 2 trips, the second Sam's", trips, err)
+	}
+}
+
+func TestNormalizeRoundTripKeepsPerson(t *testing.T) {
+	for _, tc := range []struct {
+		name, csv string
+	}{
+		{"with destination", "Name,Start,End,Country\n" +
+			"Sam,15.09.2023,20.09.2023,France\n" +
+			"Alex,25.05.2023,10.08.2023,Spain\n"},
+		{"without destination", "Name,Start,End\n" +
+			"Sam,15.09.2023,20.09.2023\n" +
+			"Alex,25.05.2023,10.08.2023\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			trips, _, err := readTripsFromCSV(writeTempCSV(t, "household.csv", tc.csv),
+				Config{PersonColumn: "Name", StartColumn: "Start", EndColumn: "End"})
+			if err != nil {
+				t.Fatalf("readTripsFromCSV: %v", err)
+			}
+
+			var normalized strings.Builder
+			if err := writeNormalizedCSV(&normalized, trips); err != nil {
+				t.Fatalf("writeNormalizedCSV: %v", err)
+			}
+
+			// Read back with no flags, as the normalized file would be
+			reread, _, err := readTripsFromCSV(writeTempCSV(t, "trips.csv", normalized.String()), Config{})
+			if err != nil {
+				t.Fatalf("readTripsFromCSV(normalized): %v", err)
+			}
+			if len(reread) != len(trips) {
+				t.Fatalf("got %d trips back, want %d:\n%s", len(reread), len(trips), normalized.String())
+			}
+			for i := range trips {
+				if reread[i].Person != trips[i].Person || reread[i].Destination != trips[i].Destination {
+					t.Errorf("trip %d: got person %q, destination %q; want %q, %q", i,
+						reread[i].Person, reread[i].Destination, trips[i].Person, trips[i].Destination)
+				}
+			}
+		})
+	}
+}
+
+func TestServeMatchesJSON(t *testing.T) {
+	path := writeTempCSV(t, "trips.csv", "Start,End\n"+
+		"01.01.2024,10.01.2024\n"+
+		"25.02.2024,05.03.2024\n"+
+		"10.04.2024,11.04.2024\n"+
+		"01.06.2024,20.06.2024\n")
+	config := parseArgs([]string{"analyze", path, "--date", "01.07.2024", "--from", "01.03.2024", "--exclude-shorter-than", "3"})
+
+	// What --json prints for the same flags
+	trips, skipped, err := readTrips(config)
+	if err != nil {
+		t.Fatalf("readTrips: %v", err)
+	}
+	trips, prepared := prepareTrips(trips, skipped, config)
+	output, err := buildJSONOutput(trips, prepared)
+	if err != nil {
+		t.Fatalf("buildJSONOutput: %v", err)
+	}
+	var want strings.Builder
+	if err := writeJSON(&want, output); err != nil {
+		t.Fatalf("writeJSON: %v", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	statusHandler(config)(recorder, httptest.NewRequest("GET", "/status", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status code = %d: %s", recorder.Code, recorder.Body)
+	}
+	if got := recorder.Body.String(); got != want.String() {
+		t.Errorf("served JSON differs from --json:\n%s\nwant:\n%s", got, want.String())
+	}
+
+	// The trip before --from is dropped, the one across it clipped and the
+	// short one excluded
+	if len(output.Trips) != 3 || !output.Trips[0].Clipped || output.Trips[1].Excluded == "" || output.Status.TotalDaysOutside != 25 {
+		t.Errorf("trips = %+v, %d days outside; want 3 trips, the first clipped, the second excluded, 25 days", output.Trips, output.Status.TotalDaysOutside)
+	}
+}
+
+func TestServeOngoingTripEndsOnRequestDate(t *testing.T) {
+	path := writeTempCSV(t, "trips.csv", "Start,End\n01.03.2026,10.03.2026\n01.10.2026,ongoing\n")
+	handler := statusHandler(parseArgs([]string{"analyze", path, "--date", "05.10.2026"}))
+
+	for _, tc := range []struct {
+		date    string
+		end     string
+		outside int
+	}{
+		{"10.10.2026", "10.10.2026", 20},
+		{"2026-10-20", "20.10.2026", 30},
+	} {
+		recorder := httptest.NewRecorder()
+		handler(recorder, httptest.NewRequest("GET", "/status?date="+tc.date, nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("?date=%s: status code = %d: %s", tc.date, recorder.Code, recorder.Body)
+		}
+		var output jsonOutput
+		if err := json.Unmarshal(recorder.Body.Bytes(), &output); err != nil {
+			t.Fatalf("?date=%s: %v", tc.date, err)
+		}
+		ongoing := output.Trips[len(output.Trips)-1]
+		if !ongoing.Ongoing || ongoing.End != tc.end || output.Status.TotalDaysOutside != tc.outside {
+			t.Errorf("?date=%s: ongoing trip ends %s with %d days outside, want %s and %d",
+				tc.date, ongoing.End, output.Status.TotalDaysOutside, tc.end, tc.outside)
+		}
+	}
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	path := writeTempCSV(t, "trips.csv", "Start,End,Country\n"+
+		"25.05.2025,10.06.2025,France\n"+
+		"15.09.2025,16.09.2025,Spain\n"+
+		"24.12.2025,04.01.2026,Italy\n"+
+		"01.08.2026,ongoing,Spain\n")
+	bundle := t.TempDir()
+
+	// analyze runs the JSON analysis for config, as main does
+	analyze := func(config Config, save bool) string {
+		t.Helper()
+		input, skipped, err := readTrips(config)
+		if err != nil {
+			t.Fatalf("readTrips: %v", err)
+		}
+		trips, config := prepareTrips(slices.Clone(input), skipped, config)
+		if save {
+			if err := writeBundleInput(input, config); err != nil {
+				t.Fatalf("writeBundleInput: %v", err)
+			}
+		}
+		output, err := buildJSONOutput(trips, config)
+		if err != nil {
+			t.Fatalf("buildJSONOutput: %v", err)
+		}
+		output.Meta.Input = ""
+		var out strings.Builder
+		writeJSON(&out, output)
+		return out.String()
+	}
+
+	want := analyze(parseArgs([]string{"analyze", path, "--date", "01.10.2026", "--from", "01.06.2025",
+		"--exclude-shorter-than", "3", "--preset", "schengen", "--rule", "60mo:450",
+		"--add-trip", "01.11.2026:10.11.2026", "--bundle", bundle}), true)
+
+	reloaded := parseArgs([]string{"analyze", filepath.Join(bundle, "trips.csv"), "--config", filepath.Join(bundle, "config.json")})
+	if got := analyze(reloaded, false); got != want {
+		t.Errorf("re-running the bundle gives:\n%s\nwant:\n%s", got, want)
+	}
+	if len(reloaded.ProjectedTrips) != 1 || len(reloaded.Rules) != 1 || reloaded.WindowDays != 180 || reloaded.ExcludeShorterThan != 3 {
+		t.Errorf("reloaded config = %+v, want the --add-trip, --rule, --preset and --exclude-shorter-than", reloaded)
+	}
+}
+
+func TestMaxStayLine(t *testing.T) {
+	target := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	config := Config{Config: absence.Config{WindowMonths: 12, AbsenceLimit: 180}, CustomDate: "01.02.2024", DateFormat: "02.01.2006"}
+
+	for _, tc := range []struct {
+		days      int
+		unlimited bool
+		maxStay   bool
+		want      string
+	}{
+		{84, false, false, "Max continuous trip from 01.02.2024: 84 days"},
+		{84, false, true, "Max continuous trip from 01.02.2024: 84 days (return by 24.04.2024)"},
+		{0, false, true, "Max continuous trip from 01.02.2024: 0 days (no allowance left)"},
+		{365, true, false, "Max continuous trip from 01.02.2024: no limit"},
+	} {
+		config.ShowMaxStay = tc.maxStay
+		if got := maxStayLine(tc.days, tc.unlimited, target, config); got != tc.want {
+			t.Errorf("maxStayLine(%d, %v) with --max-stay %v = %q, want %q", tc.days, tc.unlimited, tc.maxStay, got, tc.want)
+		}
+	}
+}
+
+func TestOutputCSVDateFormat(t *testing.T) {
+	path := writeTempCSV(t, "trips.csv", "01.03.2024,10.03.2024\n")
+	config := parseArgs([]string{"analyze", path, "--csv-out", "--date", "01.04.2024", "--out-date-format", "iso"})
+	trips, _, err := readTripsFromCSV(path, config)
+	if err != nil {
+		t.Fatalf("readTripsFromCSV: %v", err)
+	}
+
+	out := captureStdout(t, func() { outputCSV(trips, config) })
+	for _, want := range []string{"2024-03-01,2024-03-10,10,", "Total,2024-04-01,"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("--csv-out with iso dates missing %q:\n%s", want, out)
+		}
+	}
+}
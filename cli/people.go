@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// unnamedPerson labels trips with an empty --person-col cell
+const unnamedPerson = "(unnamed)"
+
+// personTrips are the trips of one person in --person-col mode
+type personTrips struct {
+	Name  string
+	Trips []Trip
+}
+
+// groupByPerson splits trips by their Person, in order of name, keeping
+// each person's trips in their original order
+func groupByPerson(trips []Trip) []personTrips {
+	byName := map[string][]Trip{}
+	var names []string
+	for _, trip := range trips {
+		name := strings.TrimSpace(trip.Person)
+		if name == "" {
+			name = unnamedPerson
+		}
+		if _, ok := byName[name]; !ok {
+			names = append(names, name)
+		}
+		byName[name] = append(byName[name], trip)
+	}
+	slices.Sort(names)
+
+	var groups []personTrips
+	for _, name := range names {
+		groups = append(groups, personTrips{name, byName[name]})
+	}
+	return groups
+}
+
+// runPerPerson runs the analysis for each person in --person-col mode: a
+// report per person under their name, or with --json an object keyed by
+// name holding each person's JSON document
+func runPerPerson(trips []Trip, config Config) {
+	groups := groupByPerson(trips)
+
+	if config.JsonOutput {
+		output := map[string]jsonOutput{}
+		for _, group := range groups {
+			personOutput, err := buildJSONOutput(group.Trips, config)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s: %v\n", group.Name, err)
+				os.Exit(1)
+			}
+			output[group.Name] = personOutput
+		}
+		if err := writeJSON(os.Stdout, output); err != nil {
+			fmt.Fprintf(os.Stderr, "Error encoding JSON: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	exceeded := false
+	for _, group := range groups {
+		if !config.JsonOutput {
+			fmt.Println(strings.Repeat("#", config.Width))
+			fmt.Printf("PERSON: %s (%d %s)\n", group.Name, len(group.Trips), plural(len(group.Trips), "trip", "trips"))
+			fmt.Println(strings.Repeat("#", config.Width))
+
+			// Different people's trips may overlap; only each person's own do
+			warnOverlaps(group.Trips, config)
+			runAnalyze(group.Trips, config)
+		}
+
+		if config.StatusBasis != "" {
+			if _, status, _ := basisStatus(group.Trips, resolveTargetDate(config), config); status == "exceeded" {
+				exceeded = true
+			}
+		}
+	}
+
+	// An explicit --status-basis makes the exit code reflect the status of
+	// anyone over the limit
+	if exceeded {
+		os.Exit(2)
+	}
+}
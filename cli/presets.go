@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"stay-within/absence"
+)
+
+// preset is a well-known absence rule selectable with --preset
+type preset struct {
+	Name        string
+	Description string
+	Rule        absence.Config
+}
+
+// presets lists the rules --preset accepts
+var presets = []preset{
+	{"uk-ilr", "UK ILR: 180 days in any 12 months (the default)", absence.Config{WindowMonths: 12, AbsenceLimit: 180}},
+	{"ilr-5yr", "UK ILR: 450 days in 5 years", absence.Config{WindowMonths: 60, AbsenceLimit: 450}},
+	{"ilr-10yr", "UK long residence ILR: 540 days in 10 years", absence.Config{WindowMonths: 120, AbsenceLimit: 540}},
+	{"citizenship", "UK naturalisation: 450 days in 5 years", absence.Config{WindowMonths: 60, AbsenceLimit: 450}},
+	{"schengen", "Schengen short stay: 90 days in any 180", absence.Config{WindowDays: 180, AbsenceLimit: 90}},
+}
+
+// applyPreset sets the window and limit from the named preset, keeping any
+// of --window, --window-days and --limit that were given explicitly
+func applyPreset(fs *flag.FlagSet, config *Config) {
+	var rule *absence.Config
+	for _, p := range presets {
+		if p.Name == config.Preset {
+			rule = &p.Rule
+		}
+	}
+	if rule == nil {
+		names := make([]string, len(presets))
+		for i, p := range presets {
+			names[i] = p.Name
+		}
+		fmt.Fprintf(os.Stderr, "Error: Unknown --preset '%s'. Use one of: %s.\n", config.Preset, strings.Join(names, ", "))
+		os.Exit(1)
+	}
+
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	// An explicit window of either kind replaces the preset's window
+	if !set["window"] && !set["window-days"] {
+		if rule.WindowMonths > 0 {
+			config.WindowMonths = rule.WindowMonths
+		}
+		config.WindowDays = rule.WindowDays
+	}
+	if !set["limit"] {
+		config.AbsenceLimit = rule.AbsenceLimit
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"stay-within/absence"
+)
+
+// overlap is a pair of trips whose date ranges share at least one day
+type overlap struct {
+	First, Second Trip
+	Days          int
+}
+
+// findOverlaps returns every pair of trips whose date ranges overlap, in
+// the order the trips appear
+func findOverlaps(trips []Trip) []overlap {
+	var overlaps []overlap
+
+	for i := range trips {
+		for j := i + 1; j < len(trips); j++ {
+			if !trips[j].Start.After(trips[i].End) && !trips[i].Start.After(trips[j].End) {
+				days := absence.DaysBetween(maxTime(trips[i].Start, trips[j].Start), minTime(trips[i].End, trips[j].End)) + 1
+				overlaps = append(overlaps, overlap{First: trips[i], Second: trips[j], Days: days})
+			}
+		}
+	}
+
+	return overlaps
+}
+
+// findOutOfOrder returns the trips that start before the trip preceding
+// them in the input
+func findOutOfOrder(trips []Trip) []Trip {
+	var outOfOrder []Trip
+	for i := 1; i < len(trips); i++ {
+		if trips[i].Start.Before(trips[i-1].Start) {
+			outOfOrder = append(outOfOrder, trips[i])
+		}
+	}
+	return outOfOrder
+}
+
+// warnOverlaps prints a warning to stderr for each pair of overlapping trips
+func warnOverlaps(trips []Trip, config Config) {
+	overlaps := findOverlaps(trips)
+	for _, o := range overlaps {
+		fmt.Fprintf(os.Stderr, "Warning: Trip %s to %s overlaps trip %s to %s (%d %s)\n",
+			o.First.Start.Format(config.DateFormat), o.First.End.Format(config.DateFormat),
+			o.Second.Start.Format(config.DateFormat), o.Second.End.Format(config.DateFormat),
+			o.Days, plural(o.Days, "day", "days"))
+	}
+	if len(overlaps) > 0 {
+		fmt.Fprintf(os.Stderr, "Overlapping days are counted once.\n\n")
+	}
+}
+
+// dataSummary counts data-quality issues found in the input
+type dataSummary struct {
+	Parsed   int
+	Skipped  int
+	Overlaps int
+	Future   int
+
+	// Filtered counts trips left out by --only-countries, --countries or
+	// --exclude-countries; they are included in Parsed
+	Filtered int
+}
+
+// summarizeData counts parsed and skipped rows, overlapping trips and trips
+// ending after the target date
+func summarizeData(trips []Trip, targetDate time.Time, config Config) dataSummary {
+	summary := dataSummary{
+		Parsed:   len(trips) + config.FilteredTrips,
+		Skipped:  config.SkippedRows,
+		Overlaps: len(findOverlaps(trips)),
+		Filtered: config.FilteredTrips,
+	}
+
+	for _, trip := range trips {
+		if trip.End.After(targetDate) {
+			summary.Future++
+		}
+	}
+
+	return summary
+}
+
+// String formats the summary as a single line,
+// e.g. "Parsed 38 trips; 2 skipped, 1 overlap, 0 future", ending with
+// "; 3 not counted by country" when a country filter left trips out
+func (s dataSummary) String() string {
+	line := fmt.Sprintf("Parsed %d %s; %d skipped, %d %s, %d future",
+		s.Parsed, plural(s.Parsed, "trip", "trips"),
+		s.Skipped, s.Overlaps, plural(s.Overlaps, "overlap", "overlaps"), s.Future)
+	if s.Filtered > 0 {
+		line += fmt.Sprintf("; %d not counted by country", s.Filtered)
+	}
+	return line
+}
+
+// Warnings returns a message for each non-zero issue count
+func (s dataSummary) Warnings() []string {
+	var warnings []string
+
+	if s.Skipped > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d %s skipped", s.Skipped, plural(s.Skipped, "row", "rows")))
+	}
+	if s.Overlaps > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d overlapping trip %s", s.Overlaps, plural(s.Overlaps, "pair", "pairs")))
+	}
+	if s.Future > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d %s ending after the target date", s.Future, plural(s.Future, "trip", "trips")))
+	}
+
+	return warnings
+}
+
+// plural returns singular when n is 1, and pluralForm otherwise
+func plural(n int, singular, pluralForm string) string {
+	if n == 1 {
+		return singular
+	}
+	return pluralForm
+}
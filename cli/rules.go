@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"stay-within/absence"
+)
+
+// Rule is one window and limit evaluated alongside the main one with --rule,
+// with its own caution threshold when WarnAt is positive
+type Rule struct {
+	Name         string
+	WindowMonths int
+	WindowDays   int
+	AbsenceLimit int
+	WarnAt       int
+}
+
+// parseRule parses a --rule value: a window and limit such as 12mo:180,
+// 5y:450 or 180d:90, or the name of a --preset, optionally followed by the
+// rule's own warn-at, e.g. 60mo:450:45 or ilr-5yr:45
+func parseRule(value string) (Rule, error) {
+	value = strings.TrimSpace(value)
+	invalid := fmt.Errorf("invalid --rule %q: use window:limit[:warn-at], e.g. 12mo:180, 5y:450:45 or 180d:90", value)
+	parts := strings.Split(value, ":")
+
+	// parseWarnAt sets rule's warn-at from the part after its window and limit
+	parseWarnAt := func(rule Rule, extra []string) (Rule, error) {
+		switch len(extra) {
+		case 0:
+			return rule, nil
+		case 1:
+			warnAt, err := strconv.Atoi(extra[0])
+			if err != nil || warnAt <= 0 {
+				return Rule{}, invalid
+			}
+			rule.WarnAt = warnAt
+			return rule, nil
+		}
+		return Rule{}, invalid
+	}
+
+	for _, p := range presets {
+		if p.Name == parts[0] {
+			return parseWarnAt(Rule{value, p.Rule.WindowMonths, p.Rule.WindowDays, p.Rule.AbsenceLimit, 0}, parts[1:])
+		}
+	}
+
+	if len(parts) < 2 {
+		return Rule{}, invalid
+	}
+	window := parts[0]
+	limit, err := strconv.Atoi(parts[1])
+	if err != nil || limit <= 0 {
+		return Rule{}, invalid
+	}
+
+	rule := Rule{Name: value, AbsenceLimit: limit}
+	for _, unit := range []struct {
+		suffix string
+		set    func(n int)
+	}{
+		{"mo", func(n int) { rule.WindowMonths = n }},
+		{"y", func(n int) { rule.WindowMonths = n * 12 }},
+		{"d", func(n int) { rule.WindowDays = n }},
+	} {
+		if number, found := strings.CutSuffix(window, unit.suffix); found {
+			n, err := strconv.Atoi(number)
+			if err != nil || n <= 0 {
+				return Rule{}, invalid
+			}
+			unit.set(n)
+			return parseWarnAt(rule, parts[2:])
+		}
+	}
+	return Rule{}, invalid
+}
+
+// ruleConfig is config with the window and limit of rule, and its warn-at
+// if it has one; otherwise --warn-at or the default threshold for its limit
+func ruleConfig(config Config, rule Rule) Config {
+	config.WindowMonths = rule.WindowMonths
+	config.WindowDays = rule.WindowDays
+	config.AbsenceLimit = rule.AbsenceLimit
+	if rule.WarnAt > 0 {
+		config.WarnAt = rule.WarnAt
+	}
+	return config
+}
+
+// ruleResult is the standing under one --rule on the target date
+type ruleResult struct {
+	Rule             Rule
+	WindowStart      time.Time
+	TotalDaysOutside int
+	DaysRemaining    int
+	Status           string
+	PeakDaysOutside  int
+	PeakWindowEnd    time.Time
+
+	// Pass is whether the limit is kept, judged on --status-basis
+	Pass bool
+}
+
+// evaluateRules evaluates each --rule on targetDate
+func evaluateRules(trips []Trip, targetDate time.Time, config Config) []ruleResult {
+	var results []ruleResult
+	for _, rule := range config.Rules {
+		rc := ruleConfig(config, rule)
+		windowStart := absence.WindowStart(targetDate, rc.Config)
+		remaining, status, _ := basisStatus(trips, targetDate, rc)
+		peak, peakEnd := absence.PeakWindow(trips, targetDate, rc.Config)
+
+		results = append(results, ruleResult{
+			Rule:             rule,
+			WindowStart:      windowStart,
+			TotalDaysOutside: absence.CalculateDaysInWindow(trips, windowStart, targetDate, config.DayCount),
+			DaysRemaining:    remaining,
+			Status:           status,
+			PeakDaysOutside:  peak,
+			PeakWindowEnd:    peakEnd,
+			Pass:             remaining >= 0,
+		})
+	}
+	return results
+}
+
+// displayRules displays a section for each --rule and whether all pass
+func displayRules(trips []Trip, config Config) {
+	targetDate := resolveTargetDate(config)
+	results := evaluateRules(trips, targetDate, config)
+
+	fmt.Println(strings.Repeat("=", config.Width))
+	fmt.Printf("RULES - As of %s\n", targetDate.Format(config.DateFormat))
+	fmt.Println(strings.Repeat("=", config.Width))
+
+	failed := 0
+	for _, r := range results {
+		rc := ruleConfig(config, r.Rule)
+		fmt.Printf("\nRule %s: %d days in any %s\n", r.Rule.Name, r.Rule.AbsenceLimit, windowLength(rc))
+		fmt.Printf("  Window: %s to %s\n", r.WindowStart.Format(config.DateFormat), targetDate.Format(config.DateFormat))
+		fmt.Printf("  Days outside: %d days (%d remaining)\n", r.TotalDaysOutside, rc.AbsenceLimit-r.TotalDaysOutside)
+		fmt.Printf("  Peak absence: %d days (window ending %s)\n", r.PeakDaysOutside, r.PeakWindowEnd.Format(config.DateFormat))
+
+		verdict := fmt.Sprintf("  %s PASS", okMark(config))
+		if r.Status == "caution" {
+			verdict = fmt.Sprintf("  %s PASS, but caution: %d days remaining (caution at %d or fewer)",
+				warnMark(config), r.DaysRemaining, absence.CautionThreshold(rc.Config))
+		}
+		if !r.Pass {
+			failed++
+			verdict = fmt.Sprintf("  %s FAIL: over the limit by %d days", warnMark(config), -r.DaysRemaining)
+		}
+		fmt.Println(colorize(verdict, r.Status, config))
+	}
+
+	fmt.Println()
+	if failed == 0 && len(results) == 1 {
+		fmt.Println(colorize(fmt.Sprintf("%s The rule passes.", okMark(config)), "ok", config))
+	} else if failed == 0 {
+		fmt.Println(colorize(fmt.Sprintf("%s All %d rules pass.", okMark(config), len(results)), "ok", config))
+	} else {
+		fmt.Println(colorize(fmt.Sprintf("%s %d of %d rules fail.", warnMark(config), failed, len(results)), "exceeded", config))
+	}
+	fmt.Println()
+}
+
+// longTrips returns the trips longer than --max-single days, in input order
+func longTrips(trips []Trip, config Config) []Trip {
+	if config.MaxSingle <= 0 {
+		return nil
+	}
+	var long []Trip
+	for _, trip := range trips {
+		if trip.Days > config.MaxSingle {
+			long = append(long, trip)
+		}
+	}
+	return long
+}
+
+// mainRule is the --window/--window-days and --limit rule as a Rule
+func mainRule(config Config) Rule {
+	name := fmt.Sprintf("%dmo:%d", config.WindowMonths, config.AbsenceLimit)
+	if config.WindowDays > 0 {
+		name = fmt.Sprintf("%dd:%d", config.WindowDays, config.AbsenceLimit)
+	}
+	return Rule{name, config.WindowMonths, config.WindowDays, config.AbsenceLimit, 0}
+}
+
+// dryRunDate is the date the --add-trip comparison is made on: the target
+// date, or the end of the last planned trip when that is later, so that
+// every planned day is in the windows compared
+func dryRunDate(targetDate time.Time, config Config) time.Time {
+	date := absence.TruncateToDay(targetDate)
+	for _, trip := range config.ProjectedTrips {
+		date = maxTime(date, trip.End)
+	}
+	return date
+}
+
+// dryRun evaluates the main rule and each --rule on the dry-run date
+// without the --add-trip trips (before) and with them (after)
+func dryRun(trips []Trip, targetDate time.Time, config Config) (date time.Time, before, after []ruleResult) {
+	var recorded []Trip
+	for _, trip := range trips {
+		if !trip.Projected {
+			recorded = append(recorded, trip)
+		}
+	}
+
+	date = dryRunDate(targetDate, config)
+	config.Rules = append([]Rule{mainRule(config)}, config.Rules...)
+	return date, evaluateRules(recorded, date, config), evaluateRules(trips, date, config)
+}
+
+// displayDryRun displays how the --add-trip trips change each rule's
+// days outside, days remaining, peak and status
+func displayDryRun(trips []Trip, config Config) {
+	date, before, after := dryRun(trips, resolveTargetDate(config), config)
+
+	fmt.Println(strings.Repeat("=", config.Width))
+	fmt.Printf("PLANNED TRIPS - Before and after, as of %s\n", date.Format(config.DateFormat))
+	fmt.Println(strings.Repeat("=", config.Width))
+
+	change := func(label string, from, to int) {
+		fmt.Printf("  %-16s %4d -> %4d (%+d)\n", label, from, to, to-from)
+	}
+	for i := range before {
+		fmt.Printf("\nRule %s\n", before[i].Rule.Name)
+		change("Days outside:", before[i].TotalDaysOutside, after[i].TotalDaysOutside)
+		change("Days remaining:", before[i].DaysRemaining, after[i].DaysRemaining)
+		change("Peak absence:", before[i].PeakDaysOutside, after[i].PeakDaysOutside)
+		fmt.Printf("  %-16s %s -> %s\n", "Status:", before[i].Status, colorize(after[i].Status, after[i].Status, config))
+	}
+	fmt.Println()
+}
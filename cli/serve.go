@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"stay-within/absence"
+)
+
+// runServe serves the JSON status on addr until the process is stopped.
+// The CSV is re-read on every request so edits show up without a restart,
+// and a ?date= query parameter overrides --date for that request.
+func runServe(config Config) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", statusHandler(config))
+
+	fmt.Fprintf(os.Stderr, "Serving status for %s at http://%s/status\n", config.Filename, config.Serve)
+	if err := http.ListenAndServe(config.Serve, mux); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// statusHandler serves the same JSON as --json for config, reading and
+// preparing the trips afresh on each request
+func statusHandler(config Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requestConfig := config
+		if date := r.URL.Query().Get("date"); date != "" {
+			if _, err := absence.ParseDate(date); err != nil {
+				http.Error(w, "invalid date parameter", http.StatusBadRequest)
+				return
+			}
+			requestConfig.CustomDate = date
+		}
+
+		// Ongoing trips run to the requested date, not the server's
+		trips, skipped, err := readTrips(requestConfig)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("error reading trips: %v", err), http.StatusInternalServerError)
+			return
+		}
+		trips, requestConfig = prepareTrips(trips, skipped, requestConfig)
+		if len(trips) == 0 {
+			http.Error(w, "no valid trip data found", http.StatusInternalServerError)
+			return
+		}
+
+		output, err := buildJSONOutput(trips, requestConfig)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		writeJSON(w, output)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"stay-within/absence"
+)
+
+// taxYearTotal is the days outside the UK in one UK tax year
+type taxYearTotal struct {
+	Start, End       time.Time
+	TotalDaysOutside int
+	DaysRemaining    int
+	Status           string
+}
+
+// jsonTaxYear is one entry of the --tax-year JSON array
+type jsonTaxYear struct {
+	TaxYear          string `json:"taxYear"`
+	Start            string `json:"start"`
+	End              string `json:"end"`
+	TotalDaysOutside int    `json:"totalDaysOutside"`
+	DaysRemaining    int    `json:"daysRemaining"`
+	Status           string `json:"status"`
+}
+
+// taxYearStart returns 6 April of the UK tax year containing t
+func taxYearStart(t time.Time) time.Time {
+	start := time.Date(t.Year(), 4, 6, 0, 0, 0, 0, time.UTC)
+	if absence.TruncateToDay(t).Before(start) {
+		start = start.AddDate(-1, 0, 0)
+	}
+	return start
+}
+
+// taxYearLabel names the tax year starting on start, e.g. "2024/25"
+func taxYearLabel(start time.Time) string {
+	return fmt.Sprintf("%d/%02d", start.Year(), (start.Year()+1)%100)
+}
+
+// taxYearTotals buckets the trips into fixed UK tax years, 6 April to
+// 5 April, from the year of the earliest trip to the year of the later of
+// the last trip and the target date
+func taxYearTotals(trips []Trip, targetDate time.Time, config Config) []taxYearTotal {
+	first, last := trips[0].Start, maxTime(trips[len(trips)-1].End, absence.TruncateToDay(targetDate))
+	for _, trip := range trips {
+		first = minTime(first, trip.Start)
+	}
+
+	var totals []taxYearTotal
+	for start := taxYearStart(first); !start.After(last); start = start.AddDate(1, 0, 0) {
+		end := start.AddDate(1, 0, -1)
+		total := absence.CalculateDaysInWindow(trips, start, end, config.DayCount)
+		remaining := config.AbsenceLimit - total
+
+		totals = append(totals, taxYearTotal{
+			Start:            start,
+			End:              end,
+			TotalDaysOutside: total,
+			DaysRemaining:    remaining,
+			Status:           absence.Status(remaining, config.Config),
+		})
+	}
+	return totals
+}
+
+// displayTaxYears displays the days outside the UK in each tax year
+func displayTaxYears(trips []Trip, config Config) {
+	totals := taxYearTotals(trips, resolveTargetDate(config), config)
+
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", config.Width))
+	fmt.Println("UK ABSENCE CALCULATOR - Tax Year Analysis (6 April to 5 April)")
+	fmt.Println(strings.Repeat("=", config.Width))
+	fmt.Println()
+	fmt.Printf("Allowed absence: %d days in each tax year\n", config.AbsenceLimit)
+	fmt.Println()
+	fmt.Println(strings.Repeat("-", config.Width))
+	fmt.Printf("%-8s | %-12s | %-12s | %-12s | %-14s\n", "Tax Year", "Start", "End", "Days Outside", "Days Remaining")
+	fmt.Println(strings.Repeat("-", config.Width))
+
+	exceeded := 0
+	for _, year := range totals {
+		fmt.Print(colorize(fmt.Sprintf("%-8s | %-12s | %-12s | %12d | %14d",
+			taxYearLabel(year.Start),
+			year.Start.Format(config.DateFormat),
+			year.End.Format(config.DateFormat),
+			year.TotalDaysOutside,
+			year.DaysRemaining), year.Status, config))
+		if year.DaysRemaining < 0 {
+			fmt.Printf("  %s over by %d days", warnMark(config), -year.DaysRemaining)
+			exceeded++
+		}
+		fmt.Println()
+	}
+	fmt.Println(strings.Repeat("-", config.Width))
+
+	if exceeded > 0 {
+		fmt.Println(colorize(fmt.Sprintf("\n%s WARNING: %d tax %s over the %d-day limit.", warnMark(config),
+			exceeded, plural(exceeded, "year is", "years are"), config.AbsenceLimit), "exceeded", config))
+	} else {
+		fmt.Println(colorize(fmt.Sprintf("\n%s Every tax year is within the %d-day limit.", okMark(config), config.AbsenceLimit), "ok", config))
+	}
+	fmt.Println()
+}
+
+// outputTaxYearsJSON outputs the tax-year totals as a JSON array
+func outputTaxYearsJSON(trips []Trip, config Config) {
+	totals := taxYearTotals(trips, resolveTargetDate(config), config)
+
+	output := make([]jsonTaxYear, 0, len(totals))
+	for _, year := range totals {
+		output = append(output, jsonTaxYear{
+			TaxYear:          taxYearLabel(year.Start),
+			Start:            year.Start.Format(config.DateFormat),
+			End:              year.End.Format(config.DateFormat),
+			TotalDaysOutside: year.TotalDaysOutside,
+			DaysRemaining:    year.DaysRemaining,
+			Status:           year.Status,
+		})
+	}
+
+	if err := writeJSON(os.Stdout, output); err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding JSON: %v\n", err)
+		os.Exit(1)
+	}
+}
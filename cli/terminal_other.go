@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+
+package main
+
+// terminalWidth returns 0 where the terminal size cannot be queried, so the
+// default width is used
+func terminalWidth() int {
+	return 0
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"stay-within/absence"
+)
+
+// Fixed parts of a minimal SpreadsheetML package with one worksheet
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>
+</Types>`
+
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Trip Analysis" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`
+
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+</Relationships>`
+
+	// Cell style 1 is a bold header, style 2 a dd.mm.yyyy date. Differential
+	// format 0 is the red fill used for breached windows.
+	xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts count="1"><numFmt numFmtId="164" formatCode="dd.mm.yyyy"/></numFmts>
+<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>
+<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="3">
+<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>
+<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>
+<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>
+</cellXfs>
+<dxfs count="1"><dxf><font><color rgb="FF9C0006"/></font><fill><patternFill><bgColor rgb="FFFFC7CE"/></patternFill></fill></dxf></dxfs>
+</styleSheet>`
+)
+
+// excelEpoch is day zero of Excel's 1900 date system
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// excelSerial converts a date to an Excel date serial number
+func excelSerial(t time.Time) int {
+	return absence.DaysBetween(excelEpoch, t)
+}
+
+// writeXLSX writes the per-trip analysis to path as an Excel workbook, with
+// the per-trip columns of --csv-out and rows whose window exceeds the limit
+// highlighted in red
+func writeXLSX(path string, trips []Trip, config Config) error {
+	var sheet strings.Builder
+	sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<cols><col min="1" max="2" width="12" customWidth="1"/><col min="3" max="5" width="16" customWidth="1"/><col min="6" max="6" width="10" customWidth="1"/></cols>
+<sheetData>
+<row r="1">`)
+
+	headers := []string{"Trip Start", "Trip End", "Days", fmt.Sprintf("Days in %s Window", windowAbbrev(config)), "Days Remaining", "Status"}
+	for i, header := range headers {
+		fmt.Fprintf(&sheet, `<c r="%c1" t="inlineStr" s="1"><is><t>%s</t></is></c>`, 'A'+i, header)
+	}
+	sheet.WriteString("</row>\n")
+
+	for i, trip := range trips {
+		row := i + 2
+		totalDaysInWindow := absence.TripWindowDays(trips, trip, config.Config)
+		remainingDays := config.AbsenceLimit - totalDaysInWindow
+
+		fmt.Fprintf(&sheet, `<row r="%d">`, row)
+		fmt.Fprintf(&sheet, `<c r="A%d" s="2"><v>%d</v></c>`, row, excelSerial(trip.Start))
+		fmt.Fprintf(&sheet, `<c r="B%d" s="2"><v>%d</v></c>`, row, excelSerial(trip.End))
+		fmt.Fprintf(&sheet, `<c r="C%d"><v>%d</v></c>`, row, trip.Days)
+		fmt.Fprintf(&sheet, `<c r="D%d"><v>%d</v></c>`, row, totalDaysInWindow)
+		fmt.Fprintf(&sheet, `<c r="E%d"><v>%d</v></c>`, row, remainingDays)
+		fmt.Fprintf(&sheet, `<c r="F%d" t="inlineStr"><is><t>%s</t></is></c>`, row, absence.Status(remainingDays, config.Config))
+		sheet.WriteString("</row>\n")
+	}
+	sheet.WriteString("</sheetData>\n")
+
+	// Highlight the whole row when its window is breached, over the limit
+	lastRow := len(trips) + 1
+	fmt.Fprintf(&sheet, `<conditionalFormatting sqref="A2:F%d"><cfRule type="expression" dxfId="0" priority="1"><formula>$E2&lt;0</formula></cfRule></conditionalFormatting>`, lastRow)
+	sheet.WriteString("\n</worksheet>")
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	archive := zip.NewWriter(file)
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/styles.xml", xlsxStyles},
+		{"xl/worksheets/sheet1.xml", sheet.String()},
+	}
+	for _, part := range parts {
+		w, err := archive.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write([]byte(part.body)); err != nil {
+			return err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
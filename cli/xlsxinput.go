@@ -0,0 +1,276 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// isXLSXInput reports whether filename is read as an Excel workbook: with
+// --input-format xlsx, or by its .xlsx extension when --input-format is not set
+func isXLSXInput(filename string, config Config) bool {
+	if config.InputFormat != "" {
+		return config.InputFormat == "xlsx"
+	}
+	return strings.EqualFold(filepath.Ext(filename), ".xlsx")
+}
+
+// readTripsFromXLSX reads trips from the first sheet of an Excel workbook,
+// or from stdin when filename is "-". The sheet's rows are parsed like CSV
+// rows, with line numbers matching the sheet's row numbers.
+func readTripsFromXLSX(filename string, config Config) ([]Trip, int, error) {
+	file := os.Stdin
+	if filename != "-" {
+		var err error
+		file, err = os.Open(filename)
+		if err != nil {
+			return nil, 0, err
+		}
+		defer file.Close()
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, 0, err
+	}
+	rows, err := readXLSXRows(data)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	for _, row := range rows {
+		if len(row) == 0 {
+			writer.Flush()
+			buf.WriteString("\n")
+			continue
+		}
+		writer.Write(row)
+	}
+	writer.Flush()
+
+	config.Delimiter = ','
+	return tripsFromCSV(buf.Bytes(), filename, config)
+}
+
+// xlsxCell is a worksheet cell: its reference such as B2, style index,
+// type and value
+type xlsxCell struct {
+	Ref    string `xml:"r,attr"`
+	Style  int    `xml:"s,attr"`
+	Type   string `xml:"t,attr"`
+	Value  string `xml:"v"`
+	Inline string `xml:"is>t"`
+}
+
+// readXLSXRows returns the cell text of the first sheet of the workbook in
+// data, one slice per sheet row, with empty rows for gaps. Cells formatted
+// as dates are converted from Excel serial numbers to yyyy-mm-dd.
+func readXLSXRows(data []byte) ([][]string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("not an .xlsx workbook: %w", err)
+	}
+	parts := map[string]*zip.File{}
+	for _, f := range archive.File {
+		parts[f.Name] = f
+	}
+	decode := func(name string, v any) error {
+		part, ok := parts[name]
+		if !ok {
+			return fmt.Errorf("workbook has no %s", name)
+		}
+		r, err := part.Open()
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		return xml.NewDecoder(r).Decode(v)
+	}
+
+	// Find the first sheet's part through the workbook relationships
+	var workbook struct {
+		Properties struct {
+			Date1904 string `xml:"date1904,attr"`
+		} `xml:"workbookPr"`
+		Sheets []struct {
+			ID string `xml:"id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decode("xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	if err := decode("xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	if len(workbook.Sheets) == 0 {
+		return nil, fmt.Errorf("workbook has no sheets")
+	}
+	sheetPart := ""
+	for _, rel := range rels.Relationships {
+		if rel.ID == workbook.Sheets[0].ID {
+			sheetPart = path.Join("xl", rel.Target)
+			if strings.HasPrefix(rel.Target, "/") {
+				sheetPart = strings.TrimPrefix(rel.Target, "/")
+			}
+		}
+	}
+
+	// Shared strings and styles are optional parts
+	var sharedStrings struct {
+		Items []struct {
+			Text string `xml:"t"`
+			Runs []struct {
+				Text string `xml:"t"`
+			} `xml:"r"`
+		} `xml:"si"`
+	}
+	var styles struct {
+		NumFmts []struct {
+			ID   int    `xml:"numFmtId,attr"`
+			Code string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		CellXfs []struct {
+			NumFmtID int `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+	if _, ok := parts["xl/sharedStrings.xml"]; ok {
+		if err := decode("xl/sharedStrings.xml", &sharedStrings); err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := parts["xl/styles.xml"]; ok {
+		if err := decode("xl/styles.xml", &styles); err != nil {
+			return nil, err
+		}
+	}
+
+	strs := make([]string, len(sharedStrings.Items))
+	for i, item := range sharedStrings.Items {
+		strs[i] = item.Text
+		for _, run := range item.Runs {
+			strs[i] += run.Text
+		}
+	}
+	customFormats := map[int]string{}
+	for _, numFmt := range styles.NumFmts {
+		customFormats[numFmt.ID] = numFmt.Code
+	}
+	dateStyles := make([]bool, len(styles.CellXfs))
+	for i, xf := range styles.CellXfs {
+		dateStyles[i] = isDateFormat(xf.NumFmtID, customFormats[xf.NumFmtID])
+	}
+
+	var sheet struct {
+		Rows []struct {
+			Number int        `xml:"r,attr"`
+			Cells  []xlsxCell `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := decode(sheetPart, &sheet); err != nil {
+		return nil, err
+	}
+
+	date1904 := workbook.Properties.Date1904 == "1" || workbook.Properties.Date1904 == "true"
+	var rows [][]string
+	for _, sheetRow := range sheet.Rows {
+		number := sheetRow.Number
+		if number == 0 {
+			number = len(rows) + 1
+		}
+		for len(rows) < number-1 {
+			rows = append(rows, nil)
+		}
+
+		var row []string
+		for _, cell := range sheetRow.Cells {
+			column := len(row)
+			if cell.Ref != "" {
+				column = xlsxColumn(cell.Ref)
+			}
+			for len(row) <= column {
+				row = append(row, "")
+			}
+			row[column] = xlsxCellText(cell, strs, dateStyles, date1904)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// xlsxCellText returns the text of cell, looking up shared strings and
+// converting date serial numbers
+func xlsxCellText(cell xlsxCell, strs []string, dateStyles []bool, date1904 bool) string {
+	switch cell.Type {
+	case "s":
+		index, err := strconv.Atoi(cell.Value)
+		if err != nil || index < 0 || index >= len(strs) {
+			return ""
+		}
+		return strs[index]
+	case "inlineStr":
+		return cell.Inline
+	case "", "n":
+		if cell.Style >= len(dateStyles) || !dateStyles[cell.Style] {
+			return cell.Value
+		}
+		serial, err := strconv.ParseFloat(cell.Value, 64)
+		if err != nil {
+			return cell.Value
+		}
+		days := int(math.Floor(serial))
+		if date1904 {
+			days += 1462
+		}
+		return excelEpoch.AddDate(0, 0, days).Format("2006-01-02")
+	default:
+		return cell.Value
+	}
+}
+
+// xlsxColumn returns the 0-based column of a cell reference such as AB12
+func xlsxColumn(ref string) int {
+	column := 0
+	for _, r := range strings.ToUpper(ref) {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		column = column*26 + int(r-'A'+1)
+	}
+	return column - 1
+}
+
+// xlsxLiteral matches the quoted text, escaped characters and [bracketed]
+// colors or conditions of a number format code, which are not placeholders
+var xlsxLiteral = regexp.MustCompile(`"[^"]*"|\\.|\[[^\]]*\]`)
+
+// isDateFormat reports whether number format id, with custom format code,
+// displays a date: one of Excel's built-in date formats, or a custom code
+// with a day or year placeholder
+func isDateFormat(id int, code string) bool {
+	switch {
+	case id >= 14 && id <= 22, id >= 27 && id <= 36, id >= 45 && id <= 47, id >= 50 && id <= 58:
+		return true
+	case code == "":
+		return false
+	}
+	code = strings.ToLower(xlsxLiteral.ReplaceAllString(code, ""))
+	return strings.ContainsAny(code, "dy")
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// yamlField is one key of a YAML mapping, kept in JSON field order
+type yamlField struct {
+	Key   string
+	Value any
+}
+
+// outputYAML outputs the same document as outputJSON, encoded as YAML
+func outputYAML(trips []Trip, config Config) {
+	output, err := buildJSONOutput(trips, config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := writeYAML(os.Stdout, output); err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding YAML: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// writeYAML encodes v as block-style YAML. It goes through JSON so that the
+// json struct tags (names, omitempty) and field order apply unchanged.
+func writeYAML(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	value, err := decodeOrdered(decoder)
+	if err != nil {
+		return err
+	}
+
+	var out strings.Builder
+	switch value := value.(type) {
+	case []yamlField, []any:
+		if isEmptyYAML(value) {
+			out.WriteString(yamlScalar(value) + "\n")
+		} else {
+			writeYAMLNode(&out, value, 0)
+		}
+	default:
+		out.WriteString(yamlScalar(value) + "\n")
+	}
+	_, err = io.WriteString(w, out.String())
+	return err
+}
+
+// decodeOrdered decodes the next JSON value, returning objects as
+// []yamlField so their key order survives
+func decodeOrdered(decoder *json.Decoder) (any, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		fields := []yamlField{}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrdered(decoder)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, yamlField{Key: key.(string), Value: value})
+		}
+		_, err = decoder.Token()
+		return fields, err
+	case json.Delim('['):
+		items := []any{}
+		for decoder.More() {
+			item, err := decodeOrdered(decoder)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		_, err = decoder.Token()
+		return items, err
+	}
+	return token, nil
+}
+
+// writeYAMLNode writes a non-empty mapping or sequence at the given indent
+func writeYAMLNode(out *strings.Builder, value any, indent int) {
+	pad := strings.Repeat(" ", indent)
+
+	switch value := value.(type) {
+	case []yamlField:
+		for _, field := range value {
+			out.WriteString(pad + yamlScalar(field.Key) + ":")
+			writeYAMLChild(out, field.Value, indent+2)
+		}
+	case []any:
+		for _, item := range value {
+			// A mapping's first key shares the line with the dash
+			if fields, ok := item.([]yamlField); ok && len(fields) > 0 {
+				var nested strings.Builder
+				writeYAMLNode(&nested, fields, indent+2)
+				out.WriteString(pad + "- " + strings.TrimPrefix(nested.String(), pad+"  "))
+				continue
+			}
+			out.WriteString(pad + "-")
+			writeYAMLChild(out, item, indent+2)
+		}
+	}
+}
+
+// writeYAMLChild finishes a "key:" or "-" line with a scalar, or continues
+// with a nested block on the following lines
+func writeYAMLChild(out *strings.Builder, value any, indent int) {
+	switch value.(type) {
+	case []yamlField, []any:
+		if !isEmptyYAML(value) {
+			out.WriteString("\n")
+			writeYAMLNode(out, value, indent)
+			return
+		}
+	}
+	out.WriteString(" " + yamlScalar(value) + "\n")
+}
+
+// isEmptyYAML reports whether value is a mapping or sequence with no entries
+func isEmptyYAML(value any) bool {
+	switch value := value.(type) {
+	case []yamlField:
+		return len(value) == 0
+	case []any:
+		return len(value) == 0
+	}
+	return false
+}
+
+// yamlPlain matches strings that are safe to write unquoted
+var yamlPlain = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_ ./()-]*$`)
+
+// yamlReserved are plain words a YAML reader would not take as strings
+var yamlReserved = map[string]bool{
+	"true": true, "false": true, "yes": true, "no": true, "on": true, "off": true,
+	"y": true, "n": true, "null": true,
+}
+
+// yamlScalar formats a decoded JSON scalar, or an empty mapping or sequence
+func yamlScalar(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(value)
+	case json.Number:
+		return value.String()
+	case string:
+		if yamlPlain.MatchString(value) && !strings.HasSuffix(value, " ") && !yamlReserved[strings.ToLower(value)] {
+			return value
+		}
+		return strconv.Quote(value)
+	case []yamlField:
+		return "{}"
+	case []any:
+		return "[]"
+	}
+	return fmt.Sprint(value)
+}
@@ -34,6 +34,9 @@ interface GoJsonStatus {
   targetDate: string;
   lastTripEnd: string;
   daysSinceLastTrip: number;
+  // Set when the last trip ends after the target date; the web calculator
+  // has no such case, so daysSinceLastTrip is only compared without it
+  daysSinceLastTripNotApplicable?: boolean;
   windowStart: string;
   windowEnd: string;
   totalDaysOutside: number;
@@ -204,7 +207,8 @@ function runCase(tc: CaseInput, index: number): boolean {
   const statusOk = [
     assertEqual('status.targetDate',       gs.targetDate,       fmtDate(ts.targetDate)),
     assertEqual('status.lastTripEnd',      gs.lastTripEnd,      fmtDate(ts.lastTripEnd)),
-    assertEqual('status.daysSinceLastTrip',gs.daysSinceLastTrip,ts.daysSinceLastTrip),
+    gs.daysSinceLastTripNotApplicable ||
+      assertEqual('status.daysSinceLastTrip',gs.daysSinceLastTrip,ts.daysSinceLastTrip),
     assertEqual('status.windowStart',      gs.windowStart,      fmtDate(ts.windowStart)),
     assertEqual('status.windowEnd',        gs.windowEnd,        fmtDate(ts.windowEnd)),
     assertEqual('status.totalDaysOutside', gs.totalDaysOutside, ts.totalDaysOutside),
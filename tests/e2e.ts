@@ -29,6 +29,9 @@ interface GoJsonStatus {
   targetDate: string;
   lastTripEnd: string;
   daysSinceLastTrip: number;
+  // Set when the last trip ends after the target date; the web calculator
+  // has no such case, so daysSinceLastTrip is only compared without it
+  daysSinceLastTripNotApplicable?: boolean;
   windowStart: string;
   windowEnd: string;
   totalDaysOutside: number;
@@ -165,7 +168,9 @@ for (const tc of testCases) {
 
   assertEqual('status.targetDate', goStatus.targetDate, fmtDate(tsStatus.targetDate));
   assertEqual('status.lastTripEnd', goStatus.lastTripEnd, fmtDate(tsStatus.lastTripEnd));
-  assertEqual('status.daysSinceLastTrip', goStatus.daysSinceLastTrip, tsStatus.daysSinceLastTrip);
+  if (!goStatus.daysSinceLastTripNotApplicable) {
+    assertEqual('status.daysSinceLastTrip', goStatus.daysSinceLastTrip, tsStatus.daysSinceLastTrip);
+  }
   assertEqual('status.windowStart', goStatus.windowStart, fmtDate(tsStatus.windowStart));
   assertEqual('status.windowEnd', goStatus.windowEnd, fmtDate(tsStatus.windowEnd));
   assertEqual('status.totalDaysOutside', goStatus.totalDaysOutside, tsStatus.totalDaysOutside);
@@ -86,6 +86,13 @@ describe('CalculatorService', () => {
         fullYear.end,
         12,
       ],
+      [
+        'overlapping trips counted once',
+        [makeTrip('01.03.2023', '10.03.2023'), makeTrip('05.03.2023', '07.03.2023')],
+        fullYear.start,
+        fullYear.end,
+        10,
+      ],
       ['single-day trip', [makeTrip('15.03.2023', '15.03.2023')], fullYear.start, fullYear.end, 1],
       [
         'trip exactly on window boundaries',
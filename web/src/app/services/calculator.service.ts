@@ -33,10 +33,11 @@ export class CalculatorService {
 
   /**
    * Calculate total absence days within a rolling window.
-   * For each trip, counts overlapping days (inclusive) with the window.
+   * Clips each trip to the window and counts the days (inclusive) covered by
+   * at least one trip, so days where trips overlap are only counted once.
    */
   calculateDaysInWindow(trips: Trip[], windowStart: Date, windowEnd: Date): number {
-    let totalDays = 0;
+    const ranges: [Date, Date][] = [];
 
     for (const trip of trips) {
       // Check if trip overlaps with window
@@ -45,12 +46,21 @@ export class CalculatorService {
       // Calculate overlap
       const overlapStart = trip.start > windowStart ? trip.start : windowStart;
       const overlapEnd = trip.end < windowEnd ? trip.end : windowEnd;
+      if (overlapEnd >= overlapStart) ranges.push([overlapStart, overlapEnd]);
+    }
 
-      // Inclusive day count
-      const daysInOverlap =
-        Math.floor((overlapEnd.getTime() - overlapStart.getTime()) / MS_PER_DAY) + 1;
+    // Merge overlapping ranges, then count days (inclusive)
+    ranges.sort((a, b) => a[0].getTime() - b[0].getTime());
 
-      totalDays += daysInOverlap;
+    let totalDays = 0;
+    let i = 0;
+    while (i < ranges.length) {
+      const start = ranges[i][0];
+      let end = ranges[i][1];
+      for (i++; i < ranges.length && ranges[i][0] <= end; i++) {
+        if (ranges[i][1] > end) end = ranges[i][1];
+      }
+      totalDays += Math.floor((end.getTime() - start.getTime()) / MS_PER_DAY) + 1;
     }
 
     return totalDays;